@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"image"
 	_ "image/png"
+	"strings"
 
 	"github.com/kettek/termfire/messages"
 )
@@ -47,6 +48,9 @@ var names = make(map[string]int)
 
 var currentFaceSet int
 
+// faceNames holds the names of faces as announced by face2 messages, as image2 messages do not contain them.
+var faceNames = make(map[int]string)
+
 func SetCurrentFaceSet(set int) {
 	if _, ok := faceSets[set]; !ok {
 		return
@@ -109,6 +113,11 @@ func AddFace(face messages.MessageFace2) bool {
 	return false
 }
 
+// AddFaceName stores the name of a face so it can be attached to the face once its image arrives.
+func AddFaceName(num int, name string) {
+	faceNames[num] = name
+}
+
 // AddFaceImage adds an image to the face map.
 func AddFaceImage(msg messages.MessageImage2) {
 	face, ok := faces[int(msg.Face)]
@@ -125,6 +134,7 @@ func AddFaceImage(msg messages.MessageImage2) {
 			Height:  msg.Height,
 			Data:    msg.Data,
 			Image:   img,
+			name:    faceNames[int(msg.Face)],
 			pending: false,
 		}
 		return
@@ -143,6 +153,18 @@ func AddFaceImage(msg messages.MessageImage2) {
 	names[face.name] = int(msg.Face)
 }
 
+// FaceLabel returns a human-friendly name for a face, stripping the trailing frame number from names such as "goblin.111".
+func FaceLabel(face *FaceImage) string {
+	if face == nil {
+		return ""
+	}
+	name := face.Name()
+	if i := strings.LastIndex(name, "."); i > 0 {
+		name = name[:i]
+	}
+	return strings.ReplaceAll(name, "_", " ")
+}
+
 type Anim struct {
 	Num   int
 	Faces []int
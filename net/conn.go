@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kettek/termfire/messages"
@@ -13,10 +14,12 @@ import (
 // Connection is a connection to a server.
 type Connection struct {
 	net.Conn
+	sendLock       sync.Mutex // Held while writing a packet, so that packets sent from different goroutines don't interleave.
 	packetId       uint16
 	OnLoss         func(error)
 	OnMessage      func(messages.Message)
 	queuedMessages []messages.Message
+	ServerTicks    bool // Whether the server accepted sending tick messages during setup.
 }
 
 // Join attempts to join the given server.
@@ -114,17 +117,37 @@ func (c *Connection) ReadBytes(buf []byte, size int) error {
 
 // Send send a message.
 func (c *Connection) Send(msg messages.Message) error {
-	bytes := msg.Bytes()
-	if len(bytes) > 0 {
-		c.Write([]byte{byte(len(bytes) >> 8), byte(len(bytes))})
-		c.Write(bytes)
-		return nil
+	return c.send(msg.Bytes())
+}
+
+// SendRaw sends a raw command string, such as "lookat 1 -2", for commands that do not have a message type.
+func (c *Connection) SendRaw(command string) error {
+	return c.send([]byte(command))
+}
+
+// send writes a packet, prefixed by its length, in a single write. It is safe to call from any goroutine.
+func (c *Connection) send(bytes []byte) error {
+	if len(bytes) == 0 {
+		return errors.New("empty message")
+	}
+	packet := make([]byte, 0, len(bytes)+2)
+	packet = append(packet, byte(len(bytes)>>8), byte(len(bytes)))
+	packet = append(packet, bytes...)
+
+	c.sendLock.Lock()
+	defer c.sendLock.Unlock()
+	if c.Conn == nil {
+		return errors.New("not connected")
 	}
-	return errors.New("empty message")
+	_, err := c.Write(packet)
+	return err
 }
 
 func (c *Connection) SendCommand(command string, repeat uint32) (uint16, error) {
-	msg := messages.MessageCommand{Command: command, Repeat: repeat, Packet: c.packetId}
+	c.sendLock.Lock()
+	packet := c.packetId
 	c.packetId++
-	return c.packetId - 1, c.Send(&msg)
+	c.sendLock.Unlock()
+	msg := messages.MessageCommand{Command: command, Repeat: repeat, Packet: packet}
+	return packet, c.Send(&msg)
 }
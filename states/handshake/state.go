@@ -36,7 +36,9 @@ func (s *State) Enter(next func(states.State)) (leave func()) {
 
 	// Setup receive just sends to actual login.
 	s.Once(&messages.MessageSetup{}, nil, func(m messages.Message, failure *messages.MessageFailure) {
-		fmt.Println("got setup message!", m.(*messages.MessageSetup), failure)
+		msg := m.(*messages.MessageSetup)
+		fmt.Println("got setup message!", msg, failure)
+		s.conn.ServerTicks = msg.Tick.Use && msg.Tick.Value != 0
 		// FIXME: Uh... do we have to handle for MessageSetup fail?
 		next(login.NewState(s.conn))
 	})
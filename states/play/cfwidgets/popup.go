@@ -43,6 +43,11 @@ func (p *PopUp) Hide() {
 	p.PopUp.Hide()
 }
 
+// SetOnHide sets a function to call whenever the popup is hidden, such as to release what it was showing.
+func (p *PopUp) SetOnHide(onHide func()) {
+	p.onHide = onHide
+}
+
 // NewPopUp creates a new PopUp widget with the given content and canvas.
 func NewPopUp(content fyne.CanvasObject, canvas fyne.Canvas) *PopUp {
 	p := &PopUp{
@@ -0,0 +1,19 @@
+package cfwidgets
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ShowToast shows a message near the top of the canvas, hiding it after the given duration or when tapped outside of.
+func ShowToast(canvas fyne.Canvas, text string, d time.Duration) {
+	label := widget.NewLabel(text)
+	label.Alignment = fyne.TextAlignCenter
+	label.TextStyle.Bold = true
+	toast := widget.NewPopUp(label, canvas)
+	size := toast.MinSize()
+	toast.ShowAtPosition(fyne.NewPos((canvas.Size().Width-size.Width)/2, canvas.Size().Height/8))
+	time.AfterFunc(d, toast.Hide)
+}
@@ -9,6 +9,8 @@ type Game struct {
 	Left     fyne.CanvasObject
 	Right    fyne.CanvasObject
 	Messages fyne.CanvasObject
+	Floor    fyne.CanvasObject // Optional strip of items beneath the player, sat atop the messages.
+	Thumbpad fyne.CanvasObject // Optional thumbpad within Left. The board only makes room for Left while it is shown, as the rest of Left is small enough to sit atop the board.
 }
 
 func (l *Game) MinSize(objects []fyne.CanvasObject) fyne.Size {
@@ -22,12 +24,27 @@ func (l *Game) Layout(objects []fyne.CanvasObject, size fyne.Size) {
 	leftWidth := remainingWidth / 2
 	rightWidth := remainingWidth - leftWidth
 
+	// The board fills the space between the side columns that are shown.
+	var boardLeft, boardRight float32
+	if l.Left.Visible() && (l.Thumbpad == nil || l.Thumbpad.Visible()) {
+		boardLeft = leftWidth
+	}
+	if l.Right.Visible() {
+		boardRight = rightWidth
+	}
+	boardWidth := size.Width - boardLeft - boardRight
+
 	l.Left.Resize(fyne.NewSize(leftWidth, size.Height))
 	l.Left.Move(fyne.NewPos(0, 0))
-	l.Board.Resize(fyne.NewSize(size.Width, size.Height))
-	l.Board.Move(fyne.NewPos((size.Width-centerSize.Width)/2, (size.Height-centerSize.Height)/2))
+	l.Board.Resize(fyne.NewSize(boardWidth, size.Height))
+	l.Board.Move(fyne.NewPos(boardLeft+(boardWidth-centerSize.Width)/2, (size.Height-centerSize.Height)/2))
 	l.Right.Resize(fyne.NewSize(rightWidth, size.Height))
 	l.Right.Move(fyne.NewPos(size.Width-rightWidth, 0))
 	l.Messages.Resize(fyne.NewSize(remainingWidth-8, size.Height/4))
 	l.Messages.Move(fyne.NewPos((size.Width-remainingWidth)/2+4, size.Height-size.Height/4))
+	if l.Floor != nil {
+		floorHeight := l.Floor.MinSize().Height
+		l.Floor.Resize(fyne.NewSize(remainingWidth-8, floorHeight))
+		l.Floor.Move(fyne.NewPos((size.Width-remainingWidth)/2+4, size.Height-size.Height/4-floorHeight))
+	}
 }
@@ -6,8 +6,10 @@ import (
 	"fmt"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
 	"github.com/kettek/mobifire/data"
 	"github.com/kettek/mobifire/states/play/cfwidgets"
+	"github.com/kettek/mobifire/states/play/managers/board"
 	"github.com/kettek/termfire/messages"
 )
 
@@ -17,6 +19,7 @@ type EntryApplyKind struct {
 	objectTag       int32  // The ID of the object. This is cached and used when possible. If the ObjectTag does not exist, the Name will be used to do a lookup.
 	OnlyIfUnapplied bool
 	Fire            bool // Whether to fire the object or not
+	Aim             bool // Whether to pick the direction to fire in on the board, rather than using the last direction moved in.
 	exists          bool // This is set to true once the action has been triggered and the object tag is known to exist.
 }
 
@@ -32,7 +35,9 @@ type EntrySpellKind struct {
 	Spell  int32
 	Name   string
 	Extra  string // Extra string to pass into the spell -- used for create food, etc.
+	Prompt bool   // Whether to ask for the extra string each time the spell is cast, rather than using Extra.
 	Ready  bool   // Whether to ready or cast the spell
+	Aim    bool   // Whether to pick the direction to cast in on the board. Aimed spells are readied and then fired.
 	exists bool   // This is set to true once the action has been triggered and the spell tag is known to exist.
 }
 
@@ -72,6 +77,9 @@ func (e Entry) TypeString() string {
 		if k.Fire {
 			str += " (fire)"
 		}
+		if k.Aim {
+			str += " (aimed)"
+		}
 	case EntrySkillKind:
 		str = "skill"
 		str += " " + k.Name
@@ -81,9 +89,17 @@ func (e Entry) TypeString() string {
 	case EntrySpellKind:
 		str = "spell"
 		str += " " + k.Name
+		if k.Prompt {
+			str += " (ask)"
+		} else if k.Extra != "" {
+			str += " " + k.Extra
+		}
 		if k.Ready {
 			str += " (ready)"
 		}
+		if k.Aim {
+			str += " (aimed)"
+		}
 	case EntryCommandKind:
 		str = "command"
 		str += " " + k.Command
@@ -236,8 +252,41 @@ func (e *Entry) Unmarshal(index int, b []byte) error {
 	return nil
 }
 
-// Trigger triggers the entry action.
+// Aimable returns if the entry fires something, and so can be aimed.
+func (e Entry) Aimable() bool {
+	switch k := e.Kind.(type) {
+	case EntryApplyKind:
+		return k.Fire
+	case EntrySpellKind:
+		return true
+	}
+	return false
+}
+
+// Aimed returns if the entry should have its direction picked on the board before firing.
+func (e Entry) Aimed() bool {
+	switch k := e.Kind.(type) {
+	case EntryApplyKind:
+		return k.Fire && k.Aim
+	case EntrySpellKind:
+		return k.Aim
+	}
+	return false
+}
+
+// Trigger triggers the entry action. Aimed entries first wait for a direction to be picked on the board, which is used for that shot alone.
 func (e Entry) Trigger(m *Manager) {
+	if e.Aimed() && m.boardManager != nil {
+		m.boardManager.Target(func(dir string) {
+			e.trigger(m, int8(board.DirectionNumber(dir)))
+		})
+		return
+	}
+	e.trigger(m, m.lastDir)
+}
+
+// trigger triggers the entry action, firing in the given direction.
+func (e Entry) trigger(m *Manager, dir int8) {
 	switch k := e.Kind.(type) {
 	case EntryApplyKind:
 		if !k.exists {
@@ -254,7 +303,7 @@ func (e Entry) Trigger(m *Manager) {
 						Tag: k.objectTag,
 					})
 				}
-				m.conn.SendCommand(fmt.Sprintf("fire %d", m.lastDir), 1)
+				m.conn.SendCommand(fmt.Sprintf("fire %d", dir), 1)
 				m.conn.SendCommand("fire_stop", 1)
 				return
 			}
@@ -273,19 +322,23 @@ func (e Entry) Trigger(m *Manager) {
 				k.exists = true
 			}
 		}
-		if k.Ready {
-			if k.Extra != "" {
-				m.conn.SendCommand(fmt.Sprintf("cast %d %s", k.Spell, k.Extra), 1)
-			} else {
-				m.conn.SendCommand(fmt.Sprintf("cast %d", k.Spell), 1)
-			}
-		} else {
-			if k.Extra != "" {
-				m.conn.SendCommand(fmt.Sprintf("invoke %d %s", k.Spell, k.Extra), 1)
-			} else {
-				m.conn.SendCommand(fmt.Sprintf("invoke %d", k.Spell), 1)
+		if k.Prompt {
+			// The prompt is answered later, so the rest of the chain continues from there, and not at all if it is cancelled.
+			spell := m.spellsManager.GetSpellByName(k.Name)
+			if spell == nil {
+				dialog.ShowError(fmt.Errorf("the spell %q is not known", k.Name), m.window)
+				return
 			}
+			m.spellsManager.PromptParameter(*spell, false, func(param string, _ bool) {
+				k.Extra = param
+				k.cast(m, dir)
+				if e.Next != nil {
+					e.Next.Trigger(m)
+				}
+			})
+			return
 		}
+		k.cast(m, dir)
 	case EntrySkillKind:
 		// TODO: Maybe add ready and use skill option? This would ensure that a talisman or holy symbol gets equipped before using the skill.
 		if k.Ready {
@@ -310,3 +363,29 @@ func (e Entry) Trigger(m *Manager) {
 		e.Next.Trigger(m)
 	}
 }
+
+// cast sends the commands to cast or ready the spell, firing aimed spells in the given direction.
+func (k EntrySpellKind) cast(m *Manager, dir int8) {
+	if k.Aim {
+		// Invoking always goes in the direction faced, so ready the spell and fire it instead.
+		if k.Extra != "" {
+			m.conn.SendCommand(fmt.Sprintf("cast %d %s", k.Spell, k.Extra), 1)
+		} else {
+			m.conn.SendCommand(fmt.Sprintf("cast %d", k.Spell), 1)
+		}
+		m.conn.SendCommand(fmt.Sprintf("fire %d", dir), 1)
+		m.conn.SendCommand("fire_stop", 1)
+	} else if k.Ready {
+		if k.Extra != "" {
+			m.conn.SendCommand(fmt.Sprintf("cast %d %s", k.Spell, k.Extra), 1)
+		} else {
+			m.conn.SendCommand(fmt.Sprintf("cast %d", k.Spell), 1)
+		}
+	} else {
+		if k.Extra != "" {
+			m.conn.SendCommand(fmt.Sprintf("invoke %d %s", k.Spell, k.Extra), 1)
+		} else {
+			m.conn.SendCommand(fmt.Sprintf("invoke %d", k.Spell), 1)
+		}
+	}
+}
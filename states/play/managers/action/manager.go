@@ -10,6 +10,7 @@ import (
 	"github.com/kettek/mobifire/net"
 	"github.com/kettek/mobifire/states/play/cfwidgets"
 	"github.com/kettek/mobifire/states/play/managers"
+	"github.com/kettek/mobifire/states/play/managers/board"
 	"github.com/kettek/mobifire/states/play/managers/items"
 	"github.com/kettek/mobifire/states/play/managers/skills"
 	"github.com/kettek/mobifire/states/play/managers/spells"
@@ -24,6 +25,7 @@ type Manager struct {
 	skillsManager *skills.Manager
 	itemsManager  *items.Manager
 	spellsManager *spells.Manager
+	boardManager  *board.Manager
 	lastDir       int8 // Last direction the player issued a movement in.
 }
 
@@ -129,6 +131,9 @@ func (m *Manager) SetManagers(managers *managers.Managers) {
 		if manager, ok := manager.(*spells.Manager); ok {
 			m.spellsManager = manager
 		}
+		if manager, ok := manager.(*board.Manager); ok {
+			m.boardManager = manager
+		}
 	}
 }
 
@@ -189,6 +194,22 @@ func (m *Manager) AcquireButton(index int) *cfwidgets.AssignableButton {
 				button.SetIcon(data.GetResource("icon_action_blank.png"))
 			}), fyne.NewMenuItemSeparator()}, actionItems...)
 
+			if entry.Aimable() {
+				aimItem := fyne.NewMenuItem("aim on board", func() {
+					switch k := entry.Kind.(type) {
+					case EntryApplyKind:
+						k.Aim = !k.Aim
+						entry.Kind = k
+					case EntrySpellKind:
+						k.Aim = !k.Aim
+						entry.Kind = k
+					}
+					m.SetAction(index, *entry)
+				})
+				aimItem.Checked = entry.Aimed()
+				actionItems = append([]*fyne.MenuItem{aimItem}, actionItems...)
+			}
+
 			currentItem.ChildMenu = fyne.NewMenu("Sub Actions", actionItems...)
 		}
 
@@ -282,18 +303,14 @@ func (m *Manager) getActionMenuItems(setAction func(Entry)) []*fyne.MenuItem {
 					action.Image = img
 				}
 				if spell.Usage > 0 {
-					entryWidget := widget.NewEntry()
-					dialog.ShowForm("Spell Parameter", "Submit", "Cancel", []*widget.FormItem{
-						{Text: "Parameter", Widget: entryWidget},
-					}, func(b bool) {
-						if b {
-							kind := action.Kind.(EntrySpellKind)
-							kind.Extra = entryWidget.Text
-							action.Kind = kind
-							setAction(action)
-							m.spellsManager.CloseSpellsList()
-						}
-					}, m.window)
+					m.spellsManager.PromptParameter(spell, true, func(param string, deferred bool) {
+						kind := action.Kind.(EntrySpellKind)
+						kind.Extra = param
+						kind.Prompt = deferred
+						action.Kind = kind
+						setAction(action)
+						m.spellsManager.CloseSpellsList()
+					})
 				} else {
 					setAction(action)
 					m.spellsManager.CloseSpellsList()
@@ -316,18 +333,14 @@ func (m *Manager) getActionMenuItems(setAction func(Entry)) []*fyne.MenuItem {
 				}
 				// TODO: Check if readied spells can have parameters... I presume they can?
 				if spell.Usage > 0 {
-					entryWidget := widget.NewEntry()
-					dialog.ShowForm("Spell Parameter", "Submit", "Cancel", []*widget.FormItem{
-						{Text: "Parameter", Widget: entryWidget},
-					}, func(b bool) {
-						if b {
-							kind := action.Kind.(EntrySpellKind)
-							kind.Extra = entryWidget.Text
-							action.Kind = kind
-							setAction(action)
-							m.spellsManager.CloseSpellsList()
-						}
-					}, m.window)
+					m.spellsManager.PromptParameter(spell, true, func(param string, deferred bool) {
+						kind := action.Kind.(EntrySpellKind)
+						kind.Extra = param
+						kind.Prompt = deferred
+						action.Kind = kind
+						setAction(action)
+						m.spellsManager.CloseSpellsList()
+					})
 				} else {
 					setAction(action)
 					m.spellsManager.CloseSpellsList()
@@ -1,9 +1,12 @@
 package board
 
 import (
+	"image"
 	"image/color"
 	"math"
 	"math/rand"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -19,29 +22,50 @@ type boardPendingImage struct {
 	Num int16
 }
 
+// targetColor is the color of the rays highlighted while targeting.
+var targetColor = color.NRGBA{255, 64, 64, 64}
+
+// scrollDuration is how long it takes to interpolate a map scroll when smooth scrolling is enabled.
+const scrollDuration = 150 * time.Millisecond
+
 type multiBoard struct {
+	mu                    sync.Mutex // Guards the boards, as ticks may come from the client clock.
 	container             *fyne.Container
 	boards                []*board
-	darkness              [][]uint8
+	light                 [][]cellLight
 	darknessOverlay       *canvas.Raster
-	scale                 float32
+	bright                bool // Bright mode raises the light of everything in view, for accessibility.
+	targetOverlay         *canvas.Raster
+	input                 *boardInput
+	scale                 float32 // The canvas scale, used to keep zoomed faces at integer pixel multiples.
+	zoom                  int     // The number of device pixels per face pixel.
 	lastWidth, lastHeight float32
 	realWidth, realHeight float32
 	cellWidth, cellHeight int
 	lastRows, lastCols    int
+	viewWidth, viewHeight int // The map size the server is sending, which determines where the player is.
 	onSizeChanged         func(rows, cols int)
+	smoothScroll          bool
+	scrollOffset          fyne.Position
+	scrollAnim            *fyne.Animation
 }
 
-func newMultiBoard(w, h, count int, cellWidth int, cellHeight int, scale float32) *multiBoard {
+func newMultiBoard(w, h, count int, cellWidth int, cellHeight int, scale float32, zoom int) *multiBoard {
+	if scale <= 0 {
+		scale = 1
+	}
 	b := &multiBoard{
 		cellWidth:  cellWidth,
 		cellHeight: cellHeight,
 		scale:      scale,
+		zoom:       zoom,
+		viewWidth:  w,
+		viewHeight: h,
 	}
 
 	var boardContainers []fyne.CanvasObject
 	for range count {
-		board := newBoard(w, h, cellWidth, cellHeight)
+		board := newBoard(w, h, cellWidth, cellHeight, b.pixelScale())
 		boardContainers = append(boardContainers, board.Container)
 		b.boards = append(b.boards, board)
 
@@ -52,27 +76,12 @@ func newMultiBoard(w, h, count int, cellWidth int, cellHeight int, scale float32
 		}
 	}
 	for range h {
-		b.darkness = append(b.darkness, make([]uint8, w))
+		b.light = append(b.light, make([]cellLight, w))
 	}
 
-	// darkness overlay
-	b.darknessOverlay = canvas.NewRasterWithPixels(func(x, y, w, h int) color.Color {
-		cellX := x / cellWidth
-		cellY := y / cellHeight
-
-		if cellX < 0 || cellX >= b.boards[0].Width || cellY < 0 || cellY >= b.boards[0].Height {
-			return color.Black
-		}
-
-		clr := color.NRGBA{0, 0, 0, 0}
-
-		darkness := b.darkness[cellY][cellX]
-
-		if darkness != 0 {
-			clr.A = 255 - darkness
-		}
-
-		return clr
+	// darkness overlay. The raster's own pixel size is used, as that includes the canvas scale and zoom.
+	b.darknessOverlay = canvas.NewRaster(func(w, h int) image.Image {
+		return lightImage(b.lightLevels(), w, h)
 	})
 
 	// A lil rasterizer test...
@@ -118,8 +127,22 @@ func newMultiBoard(w, h, count int, cellWidth int, cellHeight int, scale float32
 		return clr
 	})*/
 
+	// targeting overlay, highlighting the rays that can be fired along.
+	b.targetOverlay = canvas.NewRasterWithPixels(func(x, y, w, h int) color.Color {
+		px, py := b.PlayerCell()
+		cellX := x * b.boards[0].Width / w
+		cellY := y * b.boards[0].Height / h
+		if OnRay(cellX-px, cellY-py) {
+			return targetColor
+		}
+		return color.Transparent
+	})
+	b.targetOverlay.Hide()
+
+	b.input = newBoardInput(b.cellSize())
+
 	//b.container = container.New(b, raster)
-	b.container = container.New(b, boardContainers...)
+	b.container = container.New(b, append(boardContainers, b.input)...)
 
 	return b
 }
@@ -128,114 +151,281 @@ func (b *multiBoard) MinSize(objects []fyne.CanvasObject) fyne.Size {
 	if b.container.Size().Width != b.lastWidth || b.container.Size().Height != b.lastHeight {
 		b.lastWidth = b.container.Size().Width
 		b.lastHeight = b.container.Size().Height
-		rows, cols := CalculateBoardSize(b.container.Size(), b.cellWidth, b.cellHeight)
-		if rows != b.lastRows || cols != b.lastCols {
-			b.lastRows = rows
-			b.lastCols = cols
-			if b.onSizeChanged != nil {
-				b.onSizeChanged(rows, cols)
-			}
-		}
+		b.checkSize()
 	}
 	return fyne.NewSize(b.realWidth, b.realHeight)
 }
 
+// checkSize calls onSizeChanged if the number of cells that fit in the container has changed.
+func (b *multiBoard) checkSize() {
+	cw, ch := b.cellSize()
+	rows, cols := CalculateBoardSize(b.container.Size(), cw, ch)
+	if rows != b.lastRows || cols != b.lastCols {
+		b.lastRows = rows
+		b.lastCols = cols
+		if b.onSizeChanged != nil {
+			b.onSizeChanged(rows, cols)
+		}
+	}
+}
+
+// pixelScale returns the size of a face pixel in canvas units.
+func (b *multiBoard) pixelScale() float32 {
+	return float32(b.zoom) / b.scale
+}
+
+// cellSize returns the size of a cell in canvas units.
+func (b *multiBoard) cellSize() (float32, float32) {
+	return float32(b.cellWidth) * b.pixelScale(), float32(b.cellHeight) * b.pixelScale()
+}
+
+// SetZoom sets the zoom level, which is the number of device pixels used for each face pixel.
+func (b *multiBoard) SetZoom(zoom int) {
+	if zoom < 1 || zoom == b.zoom {
+		return
+	}
+	b.zoom = zoom
+	for _, board := range b.boards {
+		board.Scale = b.pixelScale()
+	}
+	cw, ch := b.cellSize()
+	b.input.cellWidth, b.input.cellHeight = cw, ch
+	b.realWidth = float32(b.boards[0].Width) * cw
+	b.realHeight = float32(b.boards[0].Height) * ch
+	b.checkSize()
+	b.container.Refresh()
+}
+
 func (b *multiBoard) Layout(objects []fyne.CanvasObject, size fyne.Size) {
 	for _, o := range objects {
-		o.Move(fyne.NewPos(0, 0))
 		//o.Move(fyne.NewPos((size.Width-b.realWidth)/2, (size.Height-b.realHeight)/2))
 		o.Resize(fyne.NewSize(b.realWidth, b.realHeight))
 	}
+	b.positionObjects()
+}
+
+// positionObjects moves the boards and darkness by the current scroll offset. The input always stays in place.
+func (b *multiBoard) positionObjects() {
+	for _, o := range b.container.Objects {
+		if o == b.input {
+			o.Move(fyne.NewPos(0, 0))
+		} else {
+			o.Move(b.scrollOffset)
+		}
+	}
+}
+
+// scroll starts interpolating the boards from their prior position to their current one.
+func (b *multiBoard) scroll(dx, dy int) {
+	if b.scrollAnim != nil {
+		b.scrollAnim.Stop()
+	}
+	cw, ch := b.cellSize()
+	start := fyne.NewPos(b.scrollOffset.X+float32(dx)*cw, b.scrollOffset.Y+float32(dy)*ch)
+	b.scrollOffset = start
+	b.positionObjects()
+	b.scrollAnim = fyne.NewAnimation(scrollDuration, func(done float32) {
+		b.scrollOffset = fyne.NewPos(start.X*(1-done), start.Y*(1-done))
+		b.positionObjects()
+	})
+	b.scrollAnim.Curve = fyne.AnimationLinear
+	b.scrollAnim.Start()
 }
 
 func (b *multiBoard) Tick(tick uint32) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	for _, board := range b.boards {
 		board.Tick(tick)
 	}
 }
 
 func (b *multiBoard) SetAnim(x, y, z int, anim *data.Anim, flags int8, speed int8) {
+	b.mu.Lock()
 	b.boards[z].SetAnim(x, y, anim, flags, speed)
+	b.mu.Unlock()
 	b.container.Refresh()
 }
 
 func (b *multiBoard) SetCell(x, y, z int, face *data.FaceImage) {
+	b.mu.Lock()
 	b.boards[z].SetFace(x, y, face)
+	b.mu.Unlock()
 	b.container.Refresh()
 }
 
 func (b *multiBoard) SetCells(x, y int, face *data.FaceImage) {
+	b.mu.Lock()
 	for _, board := range b.boards {
 		board.SetFace(x, y, face)
 	}
+	if face == nil && y >= 0 && y < len(b.light) && x >= 0 && x < len(b.light[y]) {
+		b.light[y][x] = cellLight{}
+	}
+	b.mu.Unlock()
 	b.container.Refresh()
 }
 
+// SetDarkness sets the light level of a cell, where 0 is pitch black and 255 is fully lit.
 func (b *multiBoard) SetDarkness(x, y int, darkness uint8) {
-	b.darkness[y][x] = darkness
+	b.mu.Lock()
+	if y >= 0 && y < len(b.light) && x >= 0 && x < len(b.light[y]) {
+		b.light[y][x] = cellLight{level: darkness, known: true}
+	}
+	b.mu.Unlock()
 	b.container.Refresh()
 }
 
+// SetBright sets whether bright mode is used.
+func (b *multiBoard) SetBright(bright bool) {
+	b.bright = bright
+	b.darknessOverlay.Refresh()
+}
+
+// SetTargeting shows or hides the targeting overlay.
+func (b *multiBoard) SetTargeting(targeting bool) {
+	if targeting {
+		b.targetOverlay.Show()
+	} else {
+		b.targetOverlay.Hide()
+	}
+}
+
+// lightLevels returns the light level to draw each cell with.
+func (b *multiBoard) lightLevels() [][]uint8 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	levels := make([][]uint8, len(b.light))
+	for y, row := range b.light {
+		levels[y] = make([]uint8, len(row))
+		for x, light := range row {
+			levels[y][x] = lightLevel(light, b.visible(x, y), b.bright)
+		}
+	}
+	return levels
+}
+
+// visible returns if any layer has a face at the given cell. The caller must hold mu.
+func (b *multiBoard) visible(x, y int) bool {
+	for _, board := range b.boards {
+		if y < board.Height && x < board.Width && board.Tiles[y][x].Face != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func (b *multiBoard) Clear() {
+	b.mu.Lock()
 	for _, board := range b.boards {
 		board.Clear()
 	}
+	for _, row := range b.light {
+		clear(row)
+	}
+	b.mu.Unlock()
 	b.container.Refresh()
 }
 
 func (b *multiBoard) ClearBoard(z int) {
+	b.mu.Lock()
 	b.boards[z].Clear()
+	b.mu.Unlock()
 	b.container.Refresh()
 }
 
-func CalculateBoardSize(size fyne.Size, cellWidth, cellHeight int) (int, int) {
-	rows := size.Width / float32(cellWidth)
-	cols := size.Height / float32(cellHeight)
+// CalculateBoardSize returns how many cells of the given size are needed to cover the given size.
+func CalculateBoardSize(size fyne.Size, cellWidth, cellHeight float32) (int, int) {
+	rows := size.Width / cellWidth
+	cols := size.Height / cellHeight
 	return int(math.Ceil(float64(rows)) + 1), int(math.Ceil(float64(cols)) + 1)
 }
 
 func (b *multiBoard) SetBoardSize(rows, cols int) {
 	// We can just fully re-create our boards since a new map is sent when map size changes.
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	b.container.RemoveAll()
 	for i := range len(b.boards) {
-		b.boards[i] = newBoard(rows, cols, b.cellWidth, b.cellHeight)
+		b.boards[i] = newBoard(rows, cols, b.cellWidth, b.cellHeight, b.pixelScale())
 		b.container.Add(b.boards[i].Container)
 	}
-	b.darkness = nil
+	b.light = nil
 	for range cols {
-		b.darkness = append(b.darkness, make([]uint8, rows))
+		b.light = append(b.light, make([]cellLight, rows))
 	}
 
-	b.realWidth = float32(rows * b.cellWidth)
-	b.realHeight = float32(cols * b.cellHeight)
+	cw, ch := b.cellSize()
+	b.realWidth = float32(rows) * cw
+	b.realHeight = float32(cols) * ch
 
 	b.container.Add(b.darknessOverlay)
+	b.container.Add(b.targetOverlay)
+	b.container.Add(b.input)
 
 	b.container.Refresh()
 }
 
-func (b *multiBoard) Shift(dx, dy int) {
-	for _, board := range b.boards {
-		board.Shift(dx, dy)
+// SetViewSize sets the size of the map view the server is sending to us.
+func (b *multiBoard) SetViewSize(w, h int) {
+	b.viewWidth = w
+	b.viewHeight = h
+}
+
+// Size returns the size of the board in cells.
+func (b *multiBoard) Size() (int, int) {
+	return b.boards[0].Width, b.boards[0].Height
+}
+
+// PlayerCell returns the cell the player occupies, which is always the center of the map view.
+func (b *multiBoard) PlayerCell() (int, int) {
+	return b.viewWidth / 2, b.viewHeight / 2
+}
+
+// Faces returns the faces at the given cell, from the top-most layer down.
+func (b *multiBoard) Faces(x, y int) []*data.FaceImage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var faces []*data.FaceImage
+	for i := len(b.boards) - 1; i >= 0; i-- {
+		board := b.boards[i]
+		if y < 0 || y >= board.Height || x < 0 || x >= board.Width {
+			continue
+		}
+		if face := board.Tiles[y][x].Face; face != nil {
+			faces = append(faces, face)
+		}
 	}
+	return faces
+}
 
+func (b *multiBoard) Shift(dx, dy int) {
 	if dx == 0 && dy == 0 {
 		return
 	}
-	var updates []darknessUpdate
+	b.mu.Lock()
+	for _, board := range b.boards {
+		board.Shift(dx, dy)
+	}
+
+	var updates []lightUpdate
 
 	for y := range b.boards[0].Height {
 		for x := range b.boards[0].Width {
 			if x+dx < 0 || x+dx >= b.boards[0].Width || y+dy < 0 || y+dy >= b.boards[0].Height {
-				updates = append(updates, darknessUpdate{x, y, 0})
+				updates = append(updates, lightUpdate{x, y, cellLight{}})
 			} else {
-				updates = append(updates, darknessUpdate{x, y, b.darkness[y+dy][x+dx]})
+				updates = append(updates, lightUpdate{x, y, b.light[y+dy][x+dx]})
 			}
 		}
 	}
 
 	for _, update := range updates {
-		b.darkness[update.y][update.x] = update.darkness
+		b.light[update.y][update.x] = update.light
+	}
+	b.mu.Unlock()
+	if b.smoothScroll {
+		b.scroll(dx, dy)
 	}
 	b.container.Refresh()
 }
@@ -243,19 +433,23 @@ func (b *multiBoard) Shift(dx, dy int) {
 type board struct {
 	Container  *fyne.Container
 	lastTick   uint64
+	animated   map[point]struct{} // Cells that have an animation, so ticks need not visit every tile.
 	Tiles      [][]*tile
 	Width      int
 	Height     int
 	CellWidth  int
 	CellHeight int
+	Scale      float32 // Canvas units per face pixel.
 }
 
-func newBoard(w, h, cellWidth, cellHeight int) *board {
+func newBoard(w, h, cellWidth, cellHeight int, scale float32) *board {
 	b := &board{
 		Width:      w,
 		Height:     h,
 		CellWidth:  cellWidth,
 		CellHeight: cellHeight,
+		Scale:      scale,
+		animated:   make(map[point]struct{}),
 	}
 
 	for range h {
@@ -271,9 +465,9 @@ func newBoard(w, h, cellWidth, cellHeight int) *board {
 	return b
 }
 
-type darknessUpdate struct {
-	x, y     int
-	darkness uint8
+type lightUpdate struct {
+	x, y  int
+	light cellLight
 }
 
 type cellUpdate struct {
@@ -313,35 +507,45 @@ func (b *board) Shift(dx, dy int) {
 		t.Flags = update.Flags
 		b.SetFace(update.x, update.y, update.Face)
 	}
+
+	// Rebuild our animated cells, as they've all moved.
+	clear(b.animated)
+	for y, row := range b.Tiles {
+		for x, t := range row {
+			if t.Anim != nil {
+				b.animated[point{x, y}] = struct{}{}
+			}
+		}
+	}
 }
 
 func (b *board) Tick(t uint32) {
 	delta := uint64(t) - b.lastTick
+	if uint64(t) < b.lastTick || b.lastTick == 0 {
+		// The clock has been reset or swapped between client and server, so just step once.
+		delta = 1
+	}
 	b.lastTick = uint64(t)
-	// FIXME: This is unnecessarily heavy, use some sort of cached animated coords.
-	for y, row := range b.Tiles {
-		for x, t := range row {
-			if t.Anim == nil {
-				continue
-			}
-			t.Counter += int(delta)
-			if t.Counter >= int(t.Speed) {
-				if t.Flags == 1 { // Randomize
-					t.Counter = 0
-					t.Frame = rand.Intn(len(t.Anim.Faces))
-				} else {
-					for t.Counter >= int(t.Speed) {
-						t.Counter -= int(t.Speed)
-						t.Frame++
-						if t.Frame >= len(t.Anim.Faces) {
-							t.Frame = 0
-						}
-					}
-				}
-				if face, ok := data.GetFace(t.Anim.Faces[t.Frame]); ok {
-					b.SetFace(x, y, face)
-				}
-			}
+	for p := range b.animated {
+		t := b.Tiles[p.Y][p.X]
+		if t.Anim == nil || len(t.Anim.Faces) == 0 {
+			delete(b.animated, p)
+			continue
+		}
+		speed := max(1, int(t.Speed))
+		t.Counter += int(delta)
+		if t.Counter < speed {
+			continue
+		}
+		if t.Flags == 1 { // Randomize
+			t.Counter = 0
+			t.Frame = rand.Intn(len(t.Anim.Faces))
+		} else {
+			t.Frame = (t.Frame + t.Counter/speed) % len(t.Anim.Faces)
+			t.Counter %= speed
+		}
+		if face, ok := data.GetFace(t.Anim.Faces[t.Frame]); ok {
+			b.SetFace(p.X, p.Y, face)
 		}
 	}
 }
@@ -356,12 +560,21 @@ func (b *board) SetFace(x, y int, img *data.FaceImage) {
 		b.Tiles[y][x].Show()
 	} else {
 		b.Tiles[y][x].Anim = nil // Clear anim if face is nil, as this _should_ signify a clear.
+		delete(b.animated, point{x, y})
 		b.Tiles[y][x].Hide()
 	}
 }
 
 func (b *board) SetAnim(x, y int, anim *data.Anim, flags int8, speed int8) {
+	if len(b.Tiles) <= y || len(b.Tiles[y]) <= x {
+		return
+	}
 	b.Tiles[y][x].Anim = anim
+	if anim != nil {
+		b.animated[point{x, y}] = struct{}{}
+	} else {
+		delete(b.animated, point{x, y})
+	}
 	b.Tiles[y][x].Speed = speed
 	b.Tiles[y][x].Flags = flags
 	// I guess set the face if we can.
@@ -373,25 +586,25 @@ func (b *board) SetAnim(x, y int, anim *data.Anim, flags int8, speed int8) {
 }
 
 func (b *board) MinSize(objects []fyne.CanvasObject) fyne.Size {
-	return fyne.NewSize(float32(b.CellWidth*b.Width), float32(b.CellHeight*b.Height))
+	return fyne.NewSize(float32(b.CellWidth*b.Width)*b.Scale, float32(b.CellHeight*b.Height)*b.Scale)
 }
 
 func (b *board) Layout(objects []fyne.CanvasObject, containerSize fyne.Size) {
 	for y := b.Height - 1; y >= 0; y-- {
 		for x := b.Width - 1; x >= 0; x-- {
 			o := b.Tiles[y][x]
-			px := float32(x * b.CellWidth)
-			py := float32(y * b.CellHeight)
+			px := float32(x*b.CellWidth) * b.Scale
+			py := float32(y*b.CellHeight) * b.Scale
 			if o.Face != nil {
-				o.Resize(fyne.NewSize(float32(o.Face.Width), float32(o.Face.Height)))
+				o.Resize(fyne.NewSize(float32(o.Face.Width)*b.Scale, float32(o.Face.Height)*b.Scale))
 				if o.Face.Width > b.CellWidth {
-					px -= float32(o.Face.Width) - float32(b.CellWidth)
+					px -= float32(o.Face.Width-b.CellWidth) * b.Scale
 				}
 				if o.Face.Height > b.CellHeight {
-					py -= float32(o.Face.Height) - float32(b.CellHeight)
+					py -= float32(o.Face.Height-b.CellHeight) * b.Scale
 				}
 			} else {
-				o.Resize(fyne.NewSize(float32(b.CellWidth), float32(b.CellHeight)))
+				o.Resize(fyne.NewSize(float32(b.CellWidth)*b.Scale, float32(b.CellHeight)*b.Scale))
 			}
 			o.Move(fyne.NewPos(px, py))
 		}
@@ -417,7 +630,8 @@ func (b *board) Clear() {
 }
 
 type tile struct {
-	widget.Icon
+	widget.BaseWidget
+	image   *canvas.Image
 	Face    *data.FaceImage
 	Anim    *data.Anim
 	Frame   int
@@ -428,8 +642,23 @@ type tile struct {
 
 // NewTile creates a new tile of the given type
 func newTile() *tile {
-	t := &tile{}
+	t := &tile{
+		image: &canvas.Image{
+			FillMode:  canvas.ImageFillStretch,
+			ScaleMode: canvas.ImageScalePixels, // Nearest-neighbour so zoomed faces stay crisp.
+		},
+	}
 	t.ExtendBaseWidget(t)
 	t.Hide()
 	return t
 }
+
+func (t *tile) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(t.image)
+}
+
+// SetResource sets the image resource of the tile.
+func (t *tile) SetResource(res fyne.Resource) {
+	t.image.Resource = res
+	t.image.Refresh()
+}
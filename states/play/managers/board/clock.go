@@ -0,0 +1,57 @@
+package board
+
+import (
+	"sync"
+	"time"
+)
+
+// tickDuration is the duration of a server tick, used when the server does not send ticks itself.
+const tickDuration = 120 * time.Millisecond
+
+// clock provides animation ticks for servers that refuse to send them.
+type clock struct {
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// Start starts calling cb every tick until Stop is called. Calling Start on a running clock does nothing.
+func (c *clock) Start(cb func(tick uint32)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	c.stop = stop
+	go func() {
+		t := time.NewTicker(tickDuration)
+		defer t.Stop()
+		tick := uint32(0)
+		for {
+			select {
+			case <-t.C:
+				cb(tick)
+				tick++
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the clock.
+func (c *clock) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+// Running returns if the clock is running.
+func (c *clock) Running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stop != nil
+}
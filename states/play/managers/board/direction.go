@@ -0,0 +1,59 @@
+package board
+
+import "math"
+
+// directions maps a delta's sign in the form of [dy+1][dx+1] to its movement command.
+var directions = [3][3]string{
+	{"northwest", "north", "northeast"},
+	{"west", "", "east"},
+	{"southwest", "south", "southeast"},
+}
+
+// directionNumbers maps movement commands to the direction numbers used by commands such as run and fire.
+var directionNumbers = map[string]int{
+	"north":     1,
+	"northeast": 2,
+	"east":      3,
+	"southeast": 4,
+	"south":     5,
+	"southwest": 6,
+	"west":      7,
+	"northwest": 8,
+}
+
+// DirectionFromDelta returns the movement command that heads from the player towards the given relative offset. An empty string is returned if the offset is the player's own cell.
+func DirectionFromDelta(dx, dy int) string {
+	return directions[sign(dy)+1][sign(dx)+1]
+}
+
+// DirectionFromVector returns the movement command whose 45 degree sector contains the given vector, such as a drag or swipe. An empty string is returned for a zero vector.
+func DirectionFromVector(dx, dy float32) string {
+	if dx == 0 && dy == 0 {
+		return ""
+	}
+	sector := math.Round(math.Atan2(float64(dy), float64(dx)) / (math.Pi / 4))
+	angle := sector * math.Pi / 4
+	return DirectionFromDelta(int(math.Round(math.Cos(angle))), int(math.Round(math.Sin(angle))))
+}
+
+// DirectionNumber returns the direction number for the given movement command, or 0 if it is not one.
+func DirectionNumber(dir string) int {
+	return directionNumbers[dir]
+}
+
+func sign(v int) int {
+	if v < 0 {
+		return -1
+	} else if v > 0 {
+		return 1
+	}
+	return 0
+}
+
+// OnRay returns if the given offset lies on one of the eight straight lines out from the player, which are the only directions things can be fired in.
+func OnRay(dx, dy int) bool {
+	if dx == 0 && dy == 0 {
+		return false
+	}
+	return dx == 0 || dy == 0 || dx == dy || dx == -dy
+}
@@ -0,0 +1,109 @@
+package board
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/mobile"
+	"fyne.io/fyne/v2/widget"
+)
+
+// boardInput is a transparent widget that sits atop the board and converts taps into cell coordinates.
+type boardInput struct {
+	widget.BaseWidget
+	cellWidth, cellHeight float32
+	onTapped              func(x, y int, event *fyne.PointEvent)
+	onTappedSecondary     func(x, y int, event *fyne.PointEvent)
+	onDoubleTapped        func(x, y int, event *fyne.PointEvent)
+	onScrolled            func(event *fyne.ScrollEvent)
+	onDragged             func(event *fyne.DragEvent)
+	onDragEnd             func()
+	onTouchDown           func()
+	onTouchUp             func()
+}
+
+func newBoardInput(cellWidth, cellHeight float32) *boardInput {
+	i := &boardInput{
+		cellWidth:  cellWidth,
+		cellHeight: cellHeight,
+	}
+	i.ExtendBaseWidget(i)
+	return i
+}
+
+func (i *boardInput) CreateRenderer() fyne.WidgetRenderer {
+	i.ExtendBaseWidget(i)
+	return widget.NewSimpleRenderer(canvas.NewRectangle(color.Transparent))
+}
+
+// cellAt returns the cell that contains the given position.
+func (i *boardInput) cellAt(pos fyne.Position) (int, int) {
+	return int(pos.X / i.cellWidth), int(pos.Y / i.cellHeight)
+}
+
+// Tapped is called on a tap or LMB click.
+func (i *boardInput) Tapped(event *fyne.PointEvent) {
+	if i.onTapped != nil {
+		x, y := i.cellAt(event.Position)
+		i.onTapped(x, y, event)
+	}
+}
+
+// TappedSecondary is called on a long-press or RMB click.
+func (i *boardInput) TappedSecondary(event *fyne.PointEvent) {
+	if i.onTappedSecondary != nil {
+		x, y := i.cellAt(event.Position)
+		i.onTappedSecondary(x, y, event)
+	}
+}
+
+// DoubleTapped is called on a double tap or double LMB click.
+func (i *boardInput) DoubleTapped(event *fyne.PointEvent) {
+	if i.onDoubleTapped != nil {
+		x, y := i.cellAt(event.Position)
+		i.onDoubleTapped(x, y, event)
+	}
+}
+
+// Scrolled is called on mouse wheel or trackpad scrolling.
+func (i *boardInput) Scrolled(event *fyne.ScrollEvent) {
+	if i.onScrolled != nil {
+		i.onScrolled(event)
+	}
+}
+
+// Dragged is called while dragging or swiping.
+func (i *boardInput) Dragged(event *fyne.DragEvent) {
+	if i.onDragged != nil {
+		i.onDragged(event)
+	}
+}
+
+// DragEnd is called when a drag or swipe ends.
+func (i *boardInput) DragEnd() {
+	if i.onDragEnd != nil {
+		i.onDragEnd()
+	}
+}
+
+// TouchDown is called when a finger touches the board on mobile.
+func (i *boardInput) TouchDown(*mobile.TouchEvent) {
+	if i.onTouchDown != nil {
+		i.onTouchDown()
+	}
+}
+
+// TouchUp is called when a finger leaves the board on mobile.
+func (i *boardInput) TouchUp(*mobile.TouchEvent) {
+	if i.onTouchUp != nil {
+		i.onTouchUp()
+	}
+}
+
+// TouchCancel is called when a finger's touch is taken over by something else on mobile.
+func (i *boardInput) TouchCancel(*mobile.TouchEvent) {
+	if i.onTouchUp != nil {
+		i.onTouchUp()
+	}
+}
@@ -0,0 +1,83 @@
+package board
+
+import (
+	"image"
+	"image/color"
+)
+
+// brightFloor is the lowest light level a visible cell may have when bright mode is enabled.
+const brightFloor = 160
+
+// cellLight is the light of a single cell as sent by the server. Map2 darkness is really a light level: 0 is pitch black and 255 is fully lit.
+type cellLight struct {
+	level uint8
+	known bool // Whether the server has sent a darkness value for the cell since it was last cleared.
+}
+
+// lightLevel returns the light level to draw a cell with. Cells the server has not sent darkness for are fully lit if something is visible in them and black otherwise, as cleared cells are out of sight. In bright mode, visible cells are raised so that nothing in view is darker than brightFloor.
+func lightLevel(light cellLight, visible bool, bright bool) uint8 {
+	level := light.level
+	if !light.known {
+		if !visible {
+			return 0
+		}
+		level = 255
+	}
+	if bright && visible {
+		level = uint8(brightFloor + int(level)*(255-brightFloor)/255)
+	}
+	return level
+}
+
+// lightImage renders a w by h pixel overlay for the given grid of light levels. Each level sits at the center of its cell and is bilinearly interpolated towards its neighbours, so light falls off smoothly rather than in hard-edged squares. The overlay is black with an alpha of the inverse of the light.
+func lightImage(levels [][]uint8, w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rows := len(levels)
+	if rows == 0 || len(levels[0]) == 0 || w <= 0 || h <= 0 {
+		return img
+	}
+	cols := len(levels[0])
+
+	xs := lightSamples(cols, w)
+	ys := lightSamples(rows, h)
+
+	for py, sy := range ys {
+		top, bottom := levels[sy.lo], levels[sy.hi]
+		for px, sx := range xs {
+			upper := lerp(float32(top[sx.lo]), float32(top[sx.hi]), sx.t)
+			lower := lerp(float32(bottom[sx.lo]), float32(bottom[sx.hi]), sx.t)
+			light := lerp(upper, lower, sy.t)
+			img.SetNRGBA(px, py, color.NRGBA{0, 0, 0, 255 - uint8(light+0.5)})
+		}
+	}
+	return img
+}
+
+// lightSample is the pair of cells a pixel falls between along one axis, and how far it is from lo to hi.
+type lightSample struct {
+	lo, hi int
+	t      float32
+}
+
+// lightSamples returns the lightSample for each of the given pixels spread across the given cells. Pixels beyond the centers of the outermost cells are clamped to them.
+func lightSamples(cells, pixels int) []lightSample {
+	samples := make([]lightSample, pixels)
+	for p := range pixels {
+		f := (float32(p)+0.5)*float32(cells)/float32(pixels) - 0.5
+		if f <= 0 {
+			samples[p] = lightSample{0, 0, 0}
+			continue
+		}
+		lo := int(f)
+		if lo >= cells-1 {
+			samples[p] = lightSample{cells - 1, cells - 1, 0}
+			continue
+		}
+		samples[p] = lightSample{lo, lo + 1, f - float32(lo)}
+	}
+	return samples
+}
+
+func lerp(a, b, t float32) float32 {
+	return a + (b-a)*t
+}
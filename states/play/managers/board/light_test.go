@@ -0,0 +1,95 @@
+package board
+
+import "testing"
+
+// alphaAt returns the overlay alpha at the given pixel of the rendered levels.
+func alphaAt(levels [][]uint8, w, h, x, y int) uint8 {
+	return lightImage(levels, w, h).NRGBAAt(x, y).A
+}
+
+func TestLightImageCellCenters(t *testing.T) {
+	// Three pixels per cell puts each cell's center exactly on a pixel: 1, 4 and 7.
+	levels := [][]uint8{{0, 128, 255}}
+	for i, x := range []int{1, 4, 7} {
+		want := 255 - levels[0][i]
+		if got := alphaAt(levels, 9, 3, x, 1); got != want {
+			t.Errorf("cell %d: alpha = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestLightImageInterpolates(t *testing.T) {
+	levels := [][]uint8{{0, 255}}
+	// Pixels 2 and 3 lie a third and two thirds of the way between the two centers.
+	a2 := alphaAt(levels, 6, 3, 2, 1)
+	a3 := alphaAt(levels, 6, 3, 3, 1)
+	if a2 != 170 || a3 != 85 {
+		t.Errorf("alphas between cells = %d, %d, want 170, 85", a2, a3)
+	}
+
+	// Vertically and horizontally at once.
+	grid := [][]uint8{
+		{0, 0},
+		{255, 255},
+	}
+	if got := alphaAt(grid, 6, 6, 1, 2); got != 170 {
+		t.Errorf("vertical alpha = %d, want 170", got)
+	}
+	grid = [][]uint8{
+		{0, 255},
+		{255, 255},
+	}
+	// A third of the way across and down: 255 - (1 - 2/3*2/3)*255 light.
+	if got := alphaAt(grid, 6, 6, 2, 2); got != 113 {
+		t.Errorf("bilinear alpha = %d, want 113", got)
+	}
+}
+
+func TestLightImageClampsEdges(t *testing.T) {
+	levels := [][]uint8{
+		{0, 255},
+		{255, 0},
+	}
+	for _, c := range []struct {
+		x, y int
+		want uint8
+	}{
+		{0, 0, 255}, // Top left corner, beyond the first cell's center.
+		{5, 0, 0},   // Top right.
+		{0, 5, 0},   // Bottom left.
+		{5, 5, 255}, // Bottom right.
+	} {
+		if got := alphaAt(levels, 6, 6, c.x, c.y); got != c.want {
+			t.Errorf("alpha at %d,%d = %d, want %d", c.x, c.y, got, c.want)
+		}
+	}
+}
+
+func TestLightImageEmpty(t *testing.T) {
+	img := lightImage(nil, 4, 4)
+	if img.NRGBAAt(0, 0).A != 0 {
+		t.Error("empty grid should render a clear overlay")
+	}
+}
+
+func TestLightLevel(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		light   cellLight
+		visible bool
+		bright  bool
+		want    uint8
+	}{
+		{"unknown and empty", cellLight{}, false, false, 0},
+		{"unknown but visible", cellLight{}, true, false, 255},
+		{"known dark", cellLight{level: 40, known: true}, true, false, 40},
+		{"fully lit", cellLight{level: 255, known: true}, true, false, 255},
+		{"fully lit bright", cellLight{level: 255, known: true}, true, true, 255},
+		{"dark bright", cellLight{level: 0, known: true}, true, true, brightFloor},
+		{"bright not visible", cellLight{level: 0, known: true}, false, true, 0},
+	} {
+		if got := lightLevel(c.light, c.visible, c.bright); got != c.want {
+			t.Errorf("%s: level = %d, want %d", c.name, got, c.want)
+		}
+	}
+}
@@ -2,27 +2,47 @@ package board
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
-	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
 	"github.com/kettek/mobifire/data"
 	"github.com/kettek/mobifire/net"
+	"github.com/kettek/mobifire/states/play/managers"
+	"github.com/kettek/mobifire/states/play/managers/items"
 	"github.com/kettek/termfire/messages"
 )
 
+// maxZoom is the largest zoom level allowed.
+const maxZoom = 8
+
 // Manager manages the game board and handles incoming messages to update the board state.
 type Manager struct {
+	app     fyne.App
 	window  fyne.Window
 	conn    *net.Connection
 	handler *messages.MessageHandler
 
-	mb *multiBoard
+	itemsManager *items.Manager
+
+	mb      *multiBoard
+	walker  *walker
+	swiper  *swiper
+	pincher *pincher
 
-	localTicker bool
+	onTarget func(dir string) // Set while targeting, called with the direction picked.
+
+	clock clock
 
 	pendingImages []boardPendingImage
+
+	// OnCommand is called after movement commands are sent from the board, so the state can track the player's direction.
+	OnCommand func(cmd string)
+	// OnThumbpadHidden is called when the thumbpad should be hidden or shown.
+	OnThumbpadHidden func(hidden bool)
 }
 
 // NewManager creates a new board manager.
@@ -40,6 +60,20 @@ func (mm *Manager) SetHandler(handler *messages.MessageHandler) {
 	mm.handler = handler
 }
 
+// SetManagers sets the managers for the manager.
+func (mm *Manager) SetManagers(managers *managers.Managers) {
+	for _, manager := range *managers {
+		if im, ok := manager.(*items.Manager); ok {
+			mm.itemsManager = im
+		}
+	}
+}
+
+// SetApp sets the app for the manager.
+func (mm *Manager) SetApp(app fyne.App) {
+	mm.app = app
+}
+
 // SetWindow sets the window for the manager.
 func (mm *Manager) SetWindow(window fyne.Window) {
 	mm.window = window
@@ -58,7 +92,8 @@ func (mm *Manager) OnFaceLoaded(faceID int16, faceImage *data.FaceImage) {
 // PreInit sets up the board and sends a setup message for map size.
 func (mm *Manager) PreInit() {
 	// Request a board size of the proper dimensions we want.
-	w, h := CalculateBoardSize(mm.window.Canvas().Size(), data.CurrentFaceSet().Width, data.CurrentFaceSet().Height)
+	scale := float32(mm.zoom()) / mm.canvasScale()
+	w, h := CalculateBoardSize(mm.window.Canvas().Size(), float32(data.CurrentFaceSet().Width)*scale, float32(data.CurrentFaceSet().Height)*scale)
 	mm.conn.Send(&messages.MessageSetup{
 		MapSize: struct {
 			Use   bool
@@ -74,7 +109,9 @@ func (mm *Manager) PreInit() {
 func (mm *Manager) Init() {
 	// Multiboard setup.
 	faceset := data.CurrentFaceSet()
-	mm.mb = newMultiBoard(11, 11, 10, faceset.Width, faceset.Height, mm.window.Canvas().Scale())
+	mm.mb = newMultiBoard(11, 11, 10, faceset.Width, faceset.Height, mm.canvasScale(), mm.zoom())
+	mm.mb.smoothScroll = mm.app.Preferences().Bool("smoothScroll")
+	mm.mb.bright = mm.app.Preferences().Bool("brightMode")
 	mm.mb.onSizeChanged = func(rows, cols int) {
 		mm.conn.Send(&messages.MessageSetup{
 			MapSize: struct {
@@ -102,10 +139,88 @@ func (mm *Manager) Init() {
 			if err != nil {
 				fmt.Println("Invalid map size:", msg.MapSize.Value)
 			}
+			mm.mb.SetViewSize(rows, cols)
 			mm.mb.SetBoardSize(rows+2, cols+2) // FIXME: CF can send beyond scope of what we can see... I'm not certain how to fix this with how Fyne does widget rendering... Maybe use canvas.Raster for the board...?
 		}
 	})
 
+	// Tap-to-walk.
+	mm.walker = newWalker(mm.mb, func(cmd string) {
+		mm.conn.SendCommand(cmd, 0)
+		if mm.OnCommand != nil {
+			mm.OnCommand(cmd)
+		}
+	})
+
+	// Board input.
+	mm.mb.input.onTapped = func(x, y int, _ *fyne.PointEvent) {
+		if mm.onTarget != nil {
+			mm.pickTarget(x, y)
+			return
+		}
+		mm.lookAt(x, y)
+	}
+	mm.mb.input.onDoubleTapped = func(x, y int, _ *fyne.PointEvent) {
+		if mm.onTarget != nil {
+			mm.pickTarget(x, y)
+			return
+		}
+		mm.WalkTo(x, y)
+	}
+	mm.mb.input.onScrolled = func(event *fyne.ScrollEvent) {
+		// Only zoom with ctrl+wheel.
+		if drv, ok := fyne.CurrentApp().Driver().(desktop.Driver); !ok || drv.CurrentKeyModifiers()&fyne.KeyModifierControl == 0 {
+			return
+		}
+		if event.Scrolled.DY > 0 {
+			mm.ZoomIn()
+		} else if event.Scrolled.DY < 0 {
+			mm.ZoomOut()
+		}
+	}
+	mm.mb.input.onTappedSecondary = func(x, y int, event *fyne.PointEvent) {
+		if mm.onTarget != nil {
+			mm.CancelTarget()
+			return
+		}
+		mm.showCellMenu(x, y, event.AbsolutePosition)
+	}
+
+	// Swipe movement.
+	mm.swiper = newSwiper(func(cmd string) {
+		mm.conn.SendCommand(cmd, 0)
+	}, func(dir string) {
+		mm.walker.Cancel()
+		if mm.OnCommand != nil {
+			mm.OnCommand(dir)
+		}
+	})
+	// Pinch zooming.
+	mm.pincher = newPincher(func(in bool) {
+		if in {
+			mm.ZoomIn()
+		} else {
+			mm.ZoomOut()
+		}
+	})
+	mm.mb.input.onTouchDown = mm.pincher.TouchDown
+	mm.mb.input.onTouchUp = mm.pincher.TouchUp
+	mm.mb.input.onDragged = func(event *fyne.DragEvent) {
+		if mm.pincher.Dragged(event) {
+			// A second finger turns a swipe into a pinch.
+			mm.swiper.Cancel()
+			return
+		}
+		if mm.SwipeMovement() {
+			mm.swiper.Dragged(event)
+		}
+	}
+	mm.mb.input.onDragEnd = func() {
+		// Always end, so a run is stopped even if swiping was turned off mid-swipe.
+		mm.swiper.DragEnd()
+		mm.pincher.DragEnd()
+	}
+
 	// Manager update handlers.
 
 	mm.handler.On(&messages.MessageMap2{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
@@ -114,6 +229,7 @@ func (mm *Manager) Init() {
 		for _, m := range msg.Coords {
 			if m.Type == messages.MessageMap2CoordTypeScrollInformation {
 				mm.mb.Shift(int(m.X), int(m.Y))
+				mm.walker.onShift(int(m.X), int(m.Y))
 			}
 
 			if len(m.Data) == 0 {
@@ -149,25 +265,239 @@ func (mm *Manager) Init() {
 	})
 	mm.handler.On(&messages.MessageNewMap{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
 		mm.mb.Clear()
+		mm.walker.onNewMap()
 	})
 
-	// Manual ticker.
-	if mm.localTicker {
-		go func() {
-			t := time.NewTicker(time.Microsecond * 120000)
-			tick := uint32(0)
-			for {
-				<-t.C
-				mm.mb.Tick(tick)
-				tick++
-			}
-		}()
+	// Ticks. If the server refused to send ticks, we run our own clock. Should a tick arrive anyway, the server's ticks take precedence.
+	tick := messages.MessageTick(0)
+	mm.handler.On(&tick, nil, func(m messages.Message, mf *messages.MessageFailure) {
+		mm.clock.Stop()
+		mm.mb.Tick(uint32(*(m.(*messages.MessageTick))))
+	})
+	if !mm.conn.ServerTicks {
+		mm.clock.Start(mm.mb.Tick)
+	}
+}
+
+// Deinit stops the client clock.
+func (mm *Manager) Deinit() {
+	mm.clock.Stop()
+	mm.walker.Cancel()
+}
+
+// SetSmoothScroll sets and stores whether map scrolls are interpolated.
+func (mm *Manager) SetSmoothScroll(smooth bool) {
+	mm.app.Preferences().SetBool("smoothScroll", smooth)
+	mm.mb.smoothScroll = smooth
+}
+
+// SetBrightMode sets and stores whether bright mode is used, which lightens dark areas that are in view.
+func (mm *Manager) SetBrightMode(bright bool) {
+	mm.app.Preferences().SetBool("brightMode", bright)
+	mm.mb.SetBright(bright)
+}
+
+// ServerMoveTo returns if walking to a cell has the server do the pathing with move_to. The server does not announce whether it supports move_to, so this is left to the user.
+func (mm *Manager) ServerMoveTo() bool {
+	return mm.app.Preferences().Bool("serverMoveTo")
+}
+
+// SetServerMoveTo sets and stores whether walking to a cell uses the server's move_to command.
+func (mm *Manager) SetServerMoveTo(server bool) {
+	mm.app.Preferences().SetBool("serverMoveTo", server)
+}
+
+// SwipeMovement returns if swiping on the board moves the player.
+func (mm *Manager) SwipeMovement() bool {
+	return mm.app.Preferences().Bool("swipeMovement")
+}
+
+// SetSwipeMovement sets and stores whether swiping on the board moves the player. The thumbpad is shown again when swiping is turned off, as it is the only other way to move on mobile.
+func (mm *Manager) SetSwipeMovement(swipe bool) {
+	mm.app.Preferences().SetBool("swipeMovement", swipe)
+	if mm.OnThumbpadHidden != nil {
+		mm.OnThumbpadHidden(mm.ThumbpadHidden())
+	}
+}
+
+// ThumbpadHidden returns if the thumbpad should be hidden, which is only allowed while swipe movement is on.
+func (mm *Manager) ThumbpadHidden() bool {
+	return mm.SwipeMovement() && mm.app.Preferences().Bool("hideThumbpad")
+}
+
+// SetThumbpadHidden sets and stores whether the thumbpad should be hidden while swipe movement is on.
+func (mm *Manager) SetThumbpadHidden(hidden bool) {
+	mm.app.Preferences().SetBool("hideThumbpad", hidden)
+	if mm.OnThumbpadHidden != nil {
+		mm.OnThumbpadHidden(mm.ThumbpadHidden())
+	}
+}
+
+// canvasScale returns the scale of the window's canvas.
+func (mm *Manager) canvasScale() float32 {
+	if scale := mm.window.Canvas().Scale(); scale > 0 {
+		return scale
+	}
+	return 1
+}
+
+// zoom returns the stored zoom level, defaulting to the canvas scale rounded to the nearest integer.
+func (mm *Manager) zoom() int {
+	fallback := max(1, int(math.Round(float64(mm.canvasScale()))))
+	return min(maxZoom, max(1, mm.app.Preferences().IntWithFallback("boardZoom", fallback)))
+}
+
+// SetZoom sets and stores the zoom level of the board. The zoom level is the number of device pixels used to draw each face pixel.
+func (mm *Manager) SetZoom(zoom int) {
+	zoom = min(maxZoom, max(1, zoom))
+	mm.app.Preferences().SetInt("boardZoom", zoom)
+	mm.mb.SetZoom(zoom)
+}
+
+// ZoomIn increases the zoom level of the board.
+func (mm *Manager) ZoomIn() {
+	mm.SetZoom(mm.zoom() + 1)
+}
+
+// ZoomOut decreases the zoom level of the board.
+func (mm *Manager) ZoomOut() {
+	mm.SetZoom(mm.zoom() - 1)
+}
+
+// lookAt sends a lookat request for the given board cell.
+func (mm *Manager) lookAt(x, y int) {
+	px, py := mm.mb.PlayerCell()
+	mm.conn.SendRaw(fmt.Sprintf("lookat %d %d", x-px, y-py))
+}
+
+// WalkTo walks the player to the given board cell. If the server's move_to command is enabled, the server does the pathing, otherwise a path is found over the visible board and stepped along.
+func (mm *Manager) WalkTo(x, y int) {
+	mm.walker.Cancel()
+	if mm.ServerMoveTo() {
+		px, py := mm.mb.PlayerCell()
+		mm.conn.SendCommand(fmt.Sprintf("move_to %d %d", x-px, y-py), 0)
+		return
+	}
+	mm.walker.WalkTo(x, y)
+}
+
+// CancelWalk stops any walk in progress. This should be called whenever the player issues some other movement.
+func (mm *Manager) CancelWalk() {
+	mm.walker.Cancel()
+}
+
+// Target highlights the eight directions that can be fired in and waits for one of them to be tapped on the board, calling cb with its direction. Tapping anywhere else, or long-pressing, cancels targeting.
+func (mm *Manager) Target(cb func(dir string)) {
+	mm.onTarget = cb
+	mm.mb.SetTargeting(true)
+}
+
+// CancelTarget stops targeting without picking a direction.
+func (mm *Manager) CancelTarget() {
+	mm.onTarget = nil
+	mm.mb.SetTargeting(false)
+}
+
+// Targeting returns if the board is waiting for a direction to be picked.
+func (mm *Manager) Targeting() bool {
+	return mm.onTarget != nil
+}
+
+// pickTarget ends targeting, calling the target callback if the given cell lies along one of the rays.
+func (mm *Manager) pickTarget(x, y int) {
+	cb := mm.onTarget
+	mm.CancelTarget()
+	px, py := mm.mb.PlayerCell()
+	if !OnRay(x-px, y-py) {
+		return
+	}
+	cb(DirectionFromDelta(x-px, y-py))
+}
+
+// showCellMenu shows the context menu for a board cell at the given absolute position.
+func (mm *Manager) showCellMenu(x, y int, pos fyne.Position) {
+	px, py := mm.mb.PlayerCell()
+	dx, dy := x-px, y-py
+	dir := DirectionFromDelta(dx, dy)
+
+	faces := mm.mb.Faces(x, y)
+	topName := "nothing"
+	if len(faces) > 0 {
+		topName = data.FaceLabel(faces[0])
+	}
+
+	var menuItems []*fyne.MenuItem
+	// List out what is in the cell, top-most first.
+	for _, face := range faces {
+		item := fyne.NewMenuItem(data.FaceLabel(face), nil)
+		item.Disabled = true
+		menuItems = append(menuItems, item)
+	}
+	if len(menuItems) > 0 {
+		menuItems = append(menuItems, fyne.NewMenuItemSeparator())
+	}
+
+	walkItem := fyne.NewMenuItem("walk here", func() {
+		mm.WalkTo(x, y)
+	})
+	fireItem := fyne.NewMenuItem("fire toward", func() {
+		mm.conn.SendCommand(fmt.Sprintf("fire %d", DirectionNumber(dir)), 0)
+		mm.conn.SendCommand("fire_stop", 0)
+		if mm.OnCommand != nil {
+			mm.OnCommand(dir)
+		}
+	})
+	// Only what is beneath the player can be applied.
+	applyItem := fyne.NewMenuItem("apply "+topName, func() {
+		mm.conn.SendCommand("apply", 0)
+	})
+	if dir == "" {
+		walkItem.Disabled = true
+		fireItem.Disabled = true
 	} else {
-		tick := messages.MessageTick(0)
-		mm.handler.On(&tick, nil, func(m messages.Message, mf *messages.MessageFailure) {
-			mm.mb.Tick(uint32(*(m.(*messages.MessageTick))))
+		applyItem.Disabled = true
+	}
+	// Only items beneath the player have tags known to the client, so only they can be examined.
+	var topItem *items.Item
+	if dir == "" && len(faces) > 0 && mm.itemsManager != nil {
+		topItem = mm.itemsManager.GetGroundItemByFace(int32(faces[0].Num))
+	}
+	examineItem := fyne.NewMenuItem("examine "+topName, func() {
+		mm.conn.Send(&messages.MessageExamine{
+			Tag: topItem.Tag,
 		})
+	})
+	if topItem == nil {
+		examineItem.Disabled = true
+	} else {
+		examineItem.Label = "examine " + topItem.GetName()
 	}
+	menuItems = append(menuItems, walkItem, fireItem, applyItem, examineItem)
+
+	smoothItem := fyne.NewMenuItem("smooth scrolling", func() {
+		mm.SetSmoothScroll(!mm.mb.smoothScroll)
+	})
+	smoothItem.Checked = mm.mb.smoothScroll
+	brightItem := fyne.NewMenuItem("bright mode", func() {
+		mm.SetBrightMode(!mm.mb.bright)
+	})
+	brightItem.Checked = mm.mb.bright
+	serverMoveToItem := fyne.NewMenuItem("server pathing", func() {
+		mm.SetServerMoveTo(!mm.ServerMoveTo())
+	})
+	serverMoveToItem.Checked = mm.ServerMoveTo()
+	swipeItem := fyne.NewMenuItem("swipe movement", func() {
+		mm.SetSwipeMovement(!mm.SwipeMovement())
+	})
+	swipeItem.Checked = mm.SwipeMovement()
+	hideThumbpadItem := fyne.NewMenuItem("hide thumbpad", func() {
+		mm.SetThumbpadHidden(!mm.ThumbpadHidden())
+	})
+	hideThumbpadItem.Checked = mm.ThumbpadHidden()
+	hideThumbpadItem.Disabled = !mm.SwipeMovement()
+	menuItems = append(menuItems, fyne.NewMenuItemSeparator(), smoothItem, brightItem, serverMoveToItem, swipeItem, hideThumbpadItem)
+
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", menuItems...), mm.window.Canvas(), pos)
 }
 
 // CanvasObject returns the canvas object for the board manager.
@@ -0,0 +1,197 @@
+package board
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kettek/mobifire/data"
+)
+
+// stepTimeout is how long to wait for the map to scroll after issuing a step before considering the step as failed.
+const stepTimeout = 1500 * time.Millisecond
+
+type point struct {
+	X, Y int
+}
+
+// walker walks the player along a client-side path towards a destination, re-planning as moves fail. Positions are kept relative to an accumulated scroll offset so that they remain stable as the map shifts underneath the player.
+type walker struct {
+	sync.Mutex
+	mb      *multiBoard
+	step    func(cmd string)
+	offset  point
+	blocked map[point]bool
+	dest    point
+	target  point // The cell we last stepped towards.
+	active  bool
+	timer   *time.Timer
+	steps   int // Counts the steps issued, so that the timeout of an earlier step is ignored.
+}
+
+func newWalker(mb *multiBoard, step func(cmd string)) *walker {
+	return &walker{
+		mb:      mb,
+		step:    step,
+		blocked: make(map[point]bool),
+	}
+}
+
+// WalkTo starts walking towards the given board cell.
+func (w *walker) WalkTo(x, y int) {
+	w.Lock()
+	defer w.Unlock()
+	w.stopTimer()
+	w.dest = point{x + w.offset.X, y + w.offset.Y}
+	w.active = true
+	w.advance()
+}
+
+// Cancel stops walking.
+func (w *walker) Cancel() {
+	w.Lock()
+	defer w.Unlock()
+	w.stop()
+}
+
+// Walking returns if the walker is currently walking.
+func (w *walker) Walking() bool {
+	w.Lock()
+	defer w.Unlock()
+	return w.active
+}
+
+func (w *walker) stop() {
+	w.stopTimer()
+	w.active = false
+}
+
+func (w *walker) stopTimer() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+}
+
+// onShift must be called whenever the map scrolls, which is how we know the player has moved.
+func (w *walker) onShift(dx, dy int) {
+	w.Lock()
+	defer w.Unlock()
+	w.offset.X += dx
+	w.offset.Y += dy
+	if !w.active {
+		return
+	}
+	w.stopTimer()
+	w.advance()
+}
+
+// onNewMap must be called when the map changes, as all of our known positions are now meaningless.
+func (w *walker) onNewMap() {
+	w.Lock()
+	defer w.Unlock()
+	w.stop()
+	w.offset = point{}
+	w.blocked = make(map[point]bool)
+}
+
+// onTimeout marks the cell we tried to step into as blocked and re-plans. Timeouts of earlier steps may still fire after the map has shifted and a new step has been issued, so those are ignored.
+func (w *walker) onTimeout(step int) {
+	w.Lock()
+	defer w.Unlock()
+	if !w.active || step != w.steps {
+		return
+	}
+	w.timer = nil
+	w.blocked[w.target] = true
+	w.advance()
+}
+
+// advance issues the next step towards the destination, stopping if it has been reached or cannot be.
+func (w *walker) advance() {
+	px, py := w.mb.PlayerCell()
+	from := point{px, py}
+	to := point{w.dest.X - w.offset.X, w.dest.Y - w.offset.Y}
+	if from == to {
+		w.stop()
+		return
+	}
+	path := w.findPath(from, to)
+	if len(path) == 0 {
+		w.stop()
+		return
+	}
+	next := path[0]
+	w.target = point{next.X + w.offset.X, next.Y + w.offset.Y}
+	w.step(DirectionFromDelta(next.X-from.X, next.Y-from.Y))
+	w.steps++
+	step := w.steps
+	w.timer = time.AfterFunc(stepTimeout, func() {
+		w.onTimeout(step)
+	})
+}
+
+// walkable returns if the given board cell can be stepped into. Cells we have never seen anything in are considered unwalkable, as are cells we have failed to move into. The server does not send which cells block movement, so cells showing a wall are considered unwalkable too.
+func (w *walker) walkable(p point) bool {
+	if w.blocked[point{p.X + w.offset.X, p.Y + w.offset.Y}] {
+		return false
+	}
+	faces := w.mb.Faces(p.X, p.Y)
+	for _, face := range faces {
+		if isWallFace(face) {
+			return false
+		}
+	}
+	return len(faces) > 0
+}
+
+// isWallFace returns if the face is that of a wall, going by the names of the standard wall archetypes.
+func isWallFace(face *data.FaceImage) bool {
+	return strings.Contains(face.Name(), "wall")
+}
+
+// findPath does a breadth-first search over the visible board, returning the cells to step through to reach the destination, excluding the starting cell. The destination itself is always considered reachable so that tapping a monster will attack it.
+func (w *walker) findPath(from, to point) []point {
+	width, height := w.mb.Size()
+	inBounds := func(p point) bool {
+		return p.X >= 0 && p.X < width && p.Y >= 0 && p.Y < height
+	}
+	if !inBounds(from) || !inBounds(to) {
+		return nil
+	}
+
+	prev := map[point]point{from: from}
+	queue := []point{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p == to {
+			break
+		}
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				n := point{p.X + dx, p.Y + dy}
+				if n == p || !inBounds(n) {
+					continue
+				}
+				if _, seen := prev[n]; seen {
+					continue
+				}
+				if n != to && !w.walkable(n) {
+					continue
+				}
+				prev[n] = p
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	if _, ok := prev[to]; !ok {
+		return nil
+	}
+	var path []point
+	for p := to; p != from; p = prev[p] {
+		path = append([]point{p}, path...)
+	}
+	return path
+}
@@ -0,0 +1,106 @@
+package board
+
+import (
+	"math"
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+const (
+	pinchStep  = 1.25 // How much the distance between fingers must grow or shrink by to zoom a level.
+	pinchMatch = 1    // How close, in canvas units, a drag must start to a finger's last position to belong to it.
+)
+
+// pincher turns two-finger pinches on the board into zooming. Fyne has no multi-touch events, but each finger is delivered to the board as its own drags, so fingers are told apart by where each drag continues from.
+type pincher struct {
+	sync.Mutex
+	zoom    func(in bool)
+	touches int             // Fingers currently down on the board.
+	fingers []fyne.Position // The last positions of up to two fingers.
+	base    float32         // The distance between the fingers when last zoomed, or 0 until both have moved.
+	pinched bool            // Whether the fingers down have pinched, so that the one left behind doesn't start swiping.
+}
+
+func newPincher(zoom func(in bool)) *pincher {
+	return &pincher{
+		zoom: zoom,
+	}
+}
+
+// TouchDown records that another finger has touched the board.
+func (p *pincher) TouchDown() {
+	p.Lock()
+	defer p.Unlock()
+	p.touches++
+}
+
+// TouchUp records that a finger has left the board, ending the pinch if fewer than two remain. Swiping is allowed again once every finger has left.
+func (p *pincher) TouchUp() {
+	p.Lock()
+	defer p.Unlock()
+	p.touches = max(0, p.touches-1)
+	if p.touches < 2 {
+		p.reset()
+	}
+	if p.touches == 0 {
+		p.pinched = false
+	}
+}
+
+// Dragged tracks the finger that moved, zooming when the fingers have spread apart or come together by pinchStep. It returns false if the fingers down have not pinched.
+func (p *pincher) Dragged(event *fyne.DragEvent) bool {
+	p.Lock()
+	defer p.Unlock()
+	if p.touches < 2 {
+		p.reset()
+		return p.pinched
+	}
+	p.pinched = true
+
+	from := event.Position.Subtract(event.Dragged)
+	nearest, nearestDist := -1, float32(math.MaxFloat32)
+	for i, finger := range p.fingers {
+		if d := distance(finger, from); d < nearestDist {
+			nearest, nearestDist = i, d
+		}
+	}
+	if nearest != -1 && (nearestDist <= pinchMatch || len(p.fingers) == 2) {
+		p.fingers[nearest] = event.Position
+	} else {
+		p.fingers = append(p.fingers, event.Position)
+	}
+	if len(p.fingers) < 2 {
+		return true
+	}
+
+	d := distance(p.fingers[0], p.fingers[1])
+	if p.base == 0 {
+		p.base = d
+	} else if d >= p.base*pinchStep {
+		p.base = d
+		p.zoom(true)
+	} else if d <= p.base/pinchStep {
+		p.base = d
+		p.zoom(false)
+	}
+	return true
+}
+
+// DragEnd forgets the fingers, as Fyne starts a new drag whenever a finger is lifted or put down.
+func (p *pincher) DragEnd() {
+	p.Lock()
+	defer p.Unlock()
+	p.reset()
+}
+
+// reset forgets the fingers. It must be called with the lock held.
+func (p *pincher) reset() {
+	p.fingers = nil
+	p.base = 0
+}
+
+func distance(a, b fyne.Position) float32 {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	return float32(math.Sqrt(float64(dx*dx + dy*dy)))
+}
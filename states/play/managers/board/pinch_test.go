@@ -0,0 +1,55 @@
+package board
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+)
+
+// drag returns a drag event moving from one position to another.
+func drag(fromX, fromY, toX, toY float32) *fyne.DragEvent {
+	return &fyne.DragEvent{
+		PointEvent: fyne.PointEvent{Position: fyne.NewPos(toX, toY)},
+		Dragged:    fyne.NewDelta(toX-fromX, toY-fromY),
+	}
+}
+
+func TestPincherZooms(t *testing.T) {
+	var zooms []bool
+	p := newPincher(func(in bool) {
+		zooms = append(zooms, in)
+	})
+
+	// A single finger never pinches.
+	if p.Dragged(drag(0, 0, 10, 0)) {
+		t.Fatal("single finger should not pinch")
+	}
+
+	p.TouchDown()
+	p.TouchDown()
+	p.Dragged(drag(100, 100, 99, 100))  // First finger.
+	p.Dragged(drag(200, 100, 201, 100)) // Second finger, 102 apart.
+	// Spread the fingers apart, alternating as the driver does.
+	p.Dragged(drag(99, 100, 80, 100))
+	p.Dragged(drag(201, 100, 220, 100)) // 140 apart.
+	if len(zooms) != 1 || !zooms[0] {
+		t.Fatalf("zooms = %v, want one zoom in", zooms)
+	}
+	// And bring them together.
+	p.Dragged(drag(80, 100, 130, 100))  // 90 apart.
+	p.Dragged(drag(220, 100, 210, 100)) // 80 apart, not yet enough to zoom again.
+	if len(zooms) != 2 || zooms[1] {
+		t.Fatalf("zooms = %v, want a zoom out after the zoom in", zooms)
+	}
+
+	// The finger left behind still counts as pinching until every finger is up.
+	p.DragEnd()
+	p.TouchUp()
+	if !p.Dragged(drag(210, 100, 150, 100)) {
+		t.Error("finger left after a pinch should not swipe")
+	}
+	p.TouchUp()
+	if p.Dragged(drag(0, 0, 50, 0)) {
+		t.Error("new drag after lifting every finger should not pinch")
+	}
+}
@@ -0,0 +1,104 @@
+package board
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+const (
+	swipeThreshold = 24                     // How far a swipe must go, in canvas units, before it points somewhere.
+	swipeHoldDelay = 300 * time.Millisecond // How long a swipe must be held before it runs rather than steps.
+)
+
+// swiper turns swipes on the board into movement. A quick swipe steps once, while swiping and holding runs until released.
+type swiper struct {
+	sync.Mutex
+	send     func(cmd string) // Sends a command to the server.
+	point    func(dir string) // Called whenever the swipe points in a new direction.
+	dragging bool
+	start    fyne.Position
+	dir      string
+	running  bool
+	timer    *time.Timer
+}
+
+func newSwiper(send func(cmd string), point func(dir string)) *swiper {
+	return &swiper{
+		send:  send,
+		point: point,
+	}
+}
+
+// Dragged updates the swipe's direction, starting the hold timer once it first leaves the threshold.
+func (s *swiper) Dragged(event *fyne.DragEvent) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.dragging {
+		s.dragging = true
+		s.start = event.Position.Subtract(event.Dragged)
+	}
+	dx, dy := event.Position.X-s.start.X, event.Position.Y-s.start.Y
+	if dx*dx+dy*dy < swipeThreshold*swipeThreshold {
+		return
+	}
+	dir := DirectionFromVector(dx, dy)
+	if dir == s.dir {
+		return
+	}
+	if s.dir == "" {
+		s.timer = time.AfterFunc(swipeHoldDelay, s.onHold)
+	}
+	s.dir = dir
+	s.point(dir)
+	if s.running {
+		s.send(fmt.Sprintf("run %d", DirectionNumber(dir)))
+	}
+}
+
+// onHold starts running if the swipe is still held.
+func (s *swiper) onHold() {
+	s.Lock()
+	defer s.Unlock()
+	s.timer = nil
+	if !s.dragging || s.dir == "" || s.running {
+		return
+	}
+	s.running = true
+	s.send(fmt.Sprintf("run %d", DirectionNumber(s.dir)))
+}
+
+// Cancel abandons the swipe without stepping, stopping any run, such as when a second finger turns it into a pinch.
+func (s *swiper) Cancel() {
+	s.Lock()
+	defer s.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if s.running {
+		s.send("run_stop")
+	}
+	s.running = false
+	s.dir = ""
+}
+
+// DragEnd stops running, or steps once if the swipe was released before it was held long enough to run.
+func (s *swiper) DragEnd() {
+	s.Lock()
+	defer s.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if s.running {
+		s.send("run_stop")
+	} else if s.dir != "" {
+		s.send(s.dir)
+	}
+	s.dragging = false
+	s.running = false
+	s.dir = ""
+}
@@ -23,6 +23,7 @@ func NewManager() *Manager {
 func (fm *Manager) Init() {
 	fm.handler.On(&messages.MessageFace2{}, nil, func(m messages.Message, failure *messages.MessageFailure) {
 		msg := m.(*messages.MessageFace2)
+		data.AddFaceName(int(msg.Num), msg.Name)
 		if _, ok := data.GetFace(int(msg.Num)); !ok {
 			fm.conn.Send(&messages.MessageAskFace{Face: int32(msg.Num)})
 		}
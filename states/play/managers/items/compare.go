@@ -0,0 +1,134 @@
+package items
+
+import (
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// lowerBetterStats are stats where a lower value is an improvement.
+var lowerBetterStats = map[string]bool{
+	"weight":       true,
+	"weapon speed": true,
+}
+
+// neutralStats are stats that are neither better nor worse when higher.
+var neutralStats = map[string]bool{
+	"value": true,
+}
+
+// statDiff is a stat of two items being compared.
+type statDiff struct {
+	Name       string
+	A, B       float64
+	HasA, HasB bool
+	Better     int // 1 if A is better, -1 if B is better, or 0 if neither.
+}
+
+// compareStats compares the stats of two examinations, in the order they appear in a and then b. A stat that only one has is treated as 0 for the other.
+func compareStats(a, b *Examination) []statDiff {
+	var diffs []statDiff
+	seen := make(map[string]bool)
+	for _, stats := range [][]ItemStat{a.Stats, b.Stats} {
+		for _, stat := range stats {
+			if seen[stat.Name] {
+				continue
+			}
+			seen[stat.Name] = true
+			diff := statDiff{Name: stat.Name}
+			diff.A, diff.HasA = a.Stat(stat.Name)
+			diff.B, diff.HasB = b.Stat(stat.Name)
+			if !neutralStats[stat.Name] && diff.A != diff.B {
+				diff.Better = 1
+				if (diff.A < diff.B) != lowerBetterStats[stat.Name] {
+					diff.Better = -1
+				}
+			}
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// appliedCounterpart returns the applied item the player has that the given item would replace. The client is only told an item's type, so items of the same type are taken to share a body slot.
+func (mgr *Manager) appliedCounterpart(item *Item) *Item {
+	for _, other := range mgr.store.Children(mgr.playerTag) {
+		if other.Tag != item.Tag && other.Flags.Applied() && other.Type == item.Type {
+			return other
+		}
+	}
+	return nil
+}
+
+// SetCompare sets whether selecting an unapplied item compares it against the applied item it would replace.
+func (iw *InventoryWidget) SetCompare(compare bool) {
+	iw.compare = compare
+	iw.inv.mgr.app.Preferences().SetBool("inventoryCompare", compare)
+	iw.refreshExamination()
+}
+
+// refreshExamination shows the selected item's examination, compared against its applied counterpart if comparing. Any examinations that are not yet known are requested.
+func (iw *InventoryWidget) refreshExamination() {
+	mgr := iw.inv.mgr
+	item := iw.inv.getItemByTag(iw.selectedTag())
+	if item == nil {
+		iw.compareTag = 0
+		iw.showExamination(nil)
+		return
+	}
+	ex := mgr.examiner.Get(item.Tag)
+	if ex == nil {
+		mgr.examiner.Examine(item.Tag)
+	}
+
+	iw.compareTag = 0
+	if iw.compare && !item.Flags.Applied() {
+		if other := mgr.appliedCounterpart(item); other != nil {
+			iw.compareTag = other.Tag
+			otherEx := mgr.examiner.Get(other.Tag)
+			if otherEx == nil {
+				mgr.examiner.Examine(other.Tag)
+			}
+			if ex != nil && otherEx != nil {
+				iw.showComparison(item, ex, other, otherEx)
+				return
+			}
+		}
+	}
+	iw.showExamination(ex)
+}
+
+// showComparison shows the stats of an item side by side with those of another, highlighting which is better for each.
+func (iw *InventoryWidget) showComparison(item *Item, ex *Examination, other *Item, otherEx *Examination) {
+	iw.itemStats.RemoveAll()
+	header := func(text string) *widget.Label {
+		label := widget.NewLabel(text)
+		label.TextStyle.Bold = true
+		label.Truncation = fyne.TextTruncateEllipsis
+		return label
+	}
+	value := func(v float64, has bool, better bool, worse bool) *widget.Label {
+		label := widget.NewLabel("-")
+		if has {
+			label.SetText(strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		if better {
+			label.Importance = widget.SuccessImportance
+		} else if worse {
+			label.Importance = widget.DangerImportance
+		}
+		return label
+	}
+
+	grid := container.NewGridWithColumns(3, header(""), header(item.GetName()), header(other.GetName()))
+	for _, diff := range compareStats(ex, otherEx) {
+		grid.Add(header(diff.Name))
+		grid.Add(value(diff.A, diff.HasA, diff.Better > 0, diff.Better < 0))
+		grid.Add(value(diff.B, diff.HasB, false, false))
+	}
+	iw.itemStats.Add(grid)
+	iw.itemStats.Refresh()
+	iw.SetExamineInfo(ex.Text)
+}
@@ -0,0 +1,162 @@
+package items
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/data"
+	"github.com/kettek/termfire/messages"
+)
+
+// capacityRegexp matches the weight limit line of a container's examine text.
+var capacityRegexp = regexp.MustCompile(`weight limit is ([\d.]+) ?kg`)
+
+// parseCapacity returns the weight limit in kg found in the given examine text, if any.
+func parseCapacity(info string) (float64, bool) {
+	match := capacityRegexp.FindStringSubmatch(info)
+	if match == nil {
+		return 0, false
+	}
+	kg, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return kg, true
+}
+
+// contentsWeight returns the weight in kg of everything in the given container.
+func contentsWeight(store *Store, tag int32) float64 {
+	var grams int64
+	for _, item := range store.Children(tag) {
+		grams += int64(item.StackWeight())
+	}
+	return float64(grams) / 1000
+}
+
+// affectsContainers returns if a change to the given tag may change the open container sections: it is an open container, one that was shown as open, or an item inside one.
+func (iw *InventoryWidget) affectsContainers(tag int32) bool {
+	shown := func(tag int32) bool {
+		return slices.ContainsFunc(iw.containerTargets, func(target dropTarget) bool {
+			return target.tag == tag
+		})
+	}
+	if shown(tag) {
+		return true
+	}
+	item := iw.inv.store.Get(tag)
+	if item == nil {
+		return false
+	}
+	return (item.Type.IsContainer() && item.Flags.Open()) || shown(item.Location)
+}
+
+// refreshContainers rebuilds the sections for each open container, hiding them entirely if none are open. While an item is being dragged, this waits until it is dropped, as the item's row may be in one of the sections.
+func (iw *InventoryWidget) refreshContainers() {
+	if iw.dragging != nil {
+		iw.containersStale = true
+		return
+	}
+	iw.containersStale = false
+	open := iw.inv.store.Find(func(item *Item) bool {
+		return item.Type.IsContainer() && item.Flags.Open()
+	})
+
+	iw.containers.RemoveAll()
+	iw.containerTargets = nil
+	for _, c := range open {
+		section := iw.makeContainerSection(c)
+		iw.containers.Add(section)
+		iw.containerTargets = append(iw.containerTargets, dropTarget{object: section, tag: c.Tag})
+	}
+
+	if len(open) == 0 {
+		iw.containersScroll.Hide()
+		return
+	}
+	iw.containersScroll.SetMinSize(fyne.NewSize(0, iw.window.Canvas().Size().Height/3))
+	iw.containersScroll.Show()
+	iw.containers.Refresh()
+}
+
+// makeContainerSection creates the section for an open container, with its weight, its contents, and actions to put the selected item in, take items out, and close it.
+func (iw *InventoryWidget) makeContainerSection(c *Item) fyne.CanvasObject {
+	readout := fmt.Sprintf("%.2fkg", contentsWeight(iw.inv.store, c.Tag))
+	capacity, _ := iw.inv.mgr.examiner.Capacity(c.Tag)
+	if capacity > 0 {
+		readout += fmt.Sprintf(" / %.1fkg", capacity)
+	}
+	header := widget.NewLabel(c.GetName() + " " + readout)
+	header.TextStyle.Bold = true
+	header.Truncation = fyne.TextTruncateEllipsis
+
+	putIn := widget.NewButtonWithIcon("", data.GetResource("icon_drop.png"), func() {
+		tag := iw.selectedTag()
+		if tag == -1 || tag == c.Tag {
+			return
+		}
+		iw.conn.Send(&messages.MessageMove{
+			To:   c.Tag,
+			Tag:  tag,
+			Nrof: 0, // all
+		})
+	})
+	closeButton := widget.NewButton("close", func() {
+		// Applying an open container closes it.
+		iw.conn.Send(&messages.MessageApply{
+			Tag: c.Tag,
+		})
+	})
+	buttons := container.NewHBox(putIn, closeButton)
+
+	// Capacity is only known from examining, so allow examining the container if it is in this inventory.
+	for i, item := range iw.inv.Items {
+		if item.Tag == c.Tag && capacity == 0 {
+			buttons.Add(widget.NewButton("?", func() {
+				iw.itemList.Select(i)
+			}))
+			break
+		}
+	}
+
+	section := container.NewVBox(container.NewBorder(nil, nil, nil, buttons, header))
+	for _, item := range iw.inv.store.Children(c.Tag) {
+		section.Add(iw.makeContainerEntry(item))
+	}
+	return section
+}
+
+// makeContainerEntry creates a row for an item within a container.
+func (iw *InventoryWidget) makeContainerEntry(item *Item) fyne.CanvasObject {
+	var icon fyne.Resource = data.GetResource("blank.png")
+	if face, ok := data.GetFace(int(item.Face)); ok {
+		icon = face
+	}
+	img := canvas.NewImageFromResource(icon)
+	img.FillMode = canvas.ImageFillContain
+	img.ScaleMode = canvas.ImageScalePixels
+	size := float32(data.CurrentFaceSet().Width)
+	img.SetMinSize(fyne.NewSize(size, size))
+
+	name := widget.NewLabel(item.GetName())
+	name.Truncation = fyne.TextTruncateEllipsis
+	weight := widget.NewLabel(formatWeight(item))
+
+	takeOut := widget.NewButtonWithIcon("", data.GetResource("icon_get.png"), func() {
+		iw.conn.Send(&messages.MessageMove{
+			To:   iw.inv.mgr.playerTag,
+			Tag:  item.Tag,
+			Nrof: 0, // all
+		})
+	})
+
+	row := container.NewBorder(nil, nil, img, container.NewHBox(weight, takeOut), name)
+	entry := newDragEntry(iw, row, iw.containersScroll.Dragged, iw.containersScroll.DragEnd)
+	entry.item = item
+	return entry
+}
@@ -0,0 +1,161 @@
+package items
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/data"
+	"github.com/kettek/mobifire/states/play/cfwidgets"
+	"github.com/kettek/mobifire/states/play/layouts"
+	"github.com/kettek/termfire/messages"
+)
+
+// bodySlot is a place on the body that applied items are shown in. The client is only told an item's type, so slots are found by the ranges of types that go in them.
+type bodySlot struct {
+	name  string
+	icon  string // Placeholder icon for when the slot is empty.
+	types [][2]messages.ItemType
+	magic bool // Whether spellcasting devices such as wands and rods go in the slot.
+}
+
+// fits returns if the given item goes in the slot.
+func (slot bodySlot) fits(item *Item) bool {
+	if slot.magic && item.Type.IsSpellCastingConsumable() {
+		return true
+	}
+	for _, r := range slot.types {
+		if item.Type >= r[0] && item.Type <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// bodySlots are laid out in a 3 wide grid, roughly in the shape of a body. Slots with no name are left empty. There are two finger slots, so rings fill them in order.
+var bodySlots = []bodySlot{
+	{name: "range", icon: "type_ranged.png", types: [][2]messages.ItemType{{150, 150}}, magic: true},
+	{name: "head", icon: "blank.png", types: [][2]messages.ItemType{{270, 270}}},
+	{name: "neck", icon: "blank.png", types: [][2]messages.ItemType{{381, 381}}},
+	{name: "weapon", icon: "type_weapon.png", types: [][2]messages.ItemType{{100, 149}}},
+	{name: "torso", icon: "type_bodyarmor.png", types: [][2]messages.ItemType{{250, 250}}},
+	{name: "shield", icon: "type_shield.png", types: [][2]messages.ItemType{{260, 260}}},
+	{name: "arms", icon: "blank.png", types: [][2]messages.ItemType{{310, 310}}},
+	{name: "cloak", icon: "type_cloak.png", types: [][2]messages.ItemType{{280, 280}}},
+	{name: "hands", icon: "blank.png", types: [][2]messages.ItemType{{300, 300}}},
+	{name: "finger", icon: "blank.png", types: [][2]messages.ItemType{{390, 390}}},
+	{name: "waist", icon: "blank.png", types: [][2]messages.ItemType{{320, 320}}},
+	{name: "finger", icon: "blank.png", types: [][2]messages.ItemType{{390, 390}}},
+	{},
+	{name: "feet", icon: "blank.png", types: [][2]messages.ItemType{{290, 290}}},
+	{},
+}
+
+// paperDoll shows the player's applied items laid out by body slot.
+type paperDoll struct {
+	mgr   *Manager
+	grid  *fyne.Container
+	popup *cfwidgets.PopUp
+}
+
+// equipped returns the applied item in each of the body slots, or nil if a slot is empty.
+func (mgr *Manager) equipped() []*Item {
+	items := make([]*Item, len(bodySlots))
+	used := make(map[int32]bool)
+	for i, slot := range bodySlots {
+		if slot.name == "" {
+			continue
+		}
+		for _, item := range mgr.store.Children(mgr.playerTag) {
+			if item.Flags.Applied() && !used[item.Tag] && slot.fits(item) {
+				items[i] = item
+				used[item.Tag] = true
+				break
+			}
+		}
+	}
+	return items
+}
+
+// ShowEquipment shows the player's applied items by body slot. Tapping a slot allows unapplying its item or swapping it for another.
+func (mgr *Manager) ShowEquipment() {
+	doll := &paperDoll{
+		mgr:  mgr,
+		grid: container.NewGridWithColumns(3),
+	}
+	mgr.doll = doll
+	doll.refresh()
+
+	dialog := layouts.NewDialog(mgr.window)
+	dialog.Full = true
+
+	content := container.NewBorder(widget.NewLabel("Equipment"), nil, nil, nil, container.NewVScroll(doll.grid))
+	doll.popup = cfwidgets.NewPopUp(container.New(dialog, content), mgr.window.Canvas())
+	// Stop refreshing the slots once they are no longer shown.
+	doll.popup.SetOnHide(func() {
+		if mgr.doll == doll {
+			mgr.doll = nil
+		}
+	})
+	doll.popup.ShowCentered(mgr.window.Canvas())
+}
+
+// refresh rebuilds the slots from the player's applied items.
+func (doll *paperDoll) refresh() {
+	mgr := doll.mgr
+	doll.grid.RemoveAll()
+	for i, item := range mgr.equipped() {
+		slot := bodySlots[i]
+		if slot.name == "" {
+			doll.grid.Add(layout.NewSpacer())
+			continue
+		}
+		var icon fyne.Resource = data.GetResource(slot.icon)
+		name := slot.name
+		if item != nil {
+			if face, ok := data.GetFace(int(item.Face)); ok {
+				icon = face
+			}
+			name = item.GetName()
+		}
+		button := cfwidgets.NewAssignableButton(icon, func() {
+			doll.tapSlot(slot, item)
+		}, nil)
+		label := widget.NewLabel(name)
+		label.Alignment = fyne.TextAlignCenter
+		label.Truncation = fyne.TextTruncateEllipsis
+		if item == nil {
+			label.Importance = widget.LowImportance
+		}
+		doll.grid.Add(container.NewBorder(nil, label, nil, nil, button))
+	}
+	doll.grid.Refresh()
+}
+
+// tapSlot offers to unapply the slot's item, if any, or to apply another that fits the slot in its place.
+func (doll *paperDoll) tapSlot(slot bodySlot, item *Item) {
+	mgr := doll.mgr
+	var menuItems []*fyne.MenuItem
+	if item != nil {
+		menuItems = append(menuItems, fyne.NewMenuItem("unapply "+item.GetName(), func() {
+			mgr.conn.Send(&messages.MessageApply{
+				Tag: item.Tag,
+			})
+		}))
+	}
+	for _, other := range mgr.store.Children(mgr.playerTag) {
+		if other.Flags.Applied() || !slot.fits(other) {
+			continue
+		}
+		// Applying an item in an occupied slot has the server swap them.
+		menuItems = append(menuItems, fyne.NewMenuItem("apply "+other.GetName(), func() {
+			mgr.conn.Send(&messages.MessageApply{
+				Tag: other.Tag,
+			})
+		}))
+	}
+	if len(menuItems) == 0 {
+		return
+	}
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu(slot.name, menuItems...), mgr.window.Canvas(), fyne.NewPos(mgr.window.Canvas().Size().Width/4, mgr.window.Canvas().Size().Height/4))
+}
@@ -0,0 +1,187 @@
+package items
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/data"
+	"github.com/kettek/termfire/messages"
+)
+
+var dropZoneColor = color.NRGBA{128, 128, 128, 96}
+
+type dragMode int
+
+const (
+	dragUndecided dragMode = iota
+	dragItem
+	dragScroll
+)
+
+// dragEntry wraps an item's row so that it can be dragged onto a drop target. Mostly vertical drags are passed on so that whatever holds the row can still be scrolled.
+type dragEntry struct {
+	widget.BaseWidget
+	iw        *InventoryWidget
+	content   fyne.CanvasObject
+	item      *Item
+	scroll    func(ev *fyne.DragEvent)
+	scrollEnd func()
+	mode      dragMode
+}
+
+func newDragEntry(iw *InventoryWidget, content fyne.CanvasObject, scroll func(ev *fyne.DragEvent), scrollEnd func()) *dragEntry {
+	entry := &dragEntry{
+		iw:        iw,
+		content:   content,
+		scroll:    scroll,
+		scrollEnd: scrollEnd,
+	}
+	entry.ExtendBaseWidget(entry)
+	return entry
+}
+
+// CreateRenderer returns a renderer for the wrapped row.
+func (entry *dragEntry) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(entry.content)
+}
+
+// Dragged starts dragging the item if the drag is mostly sideways, otherwise it scrolls.
+func (entry *dragEntry) Dragged(ev *fyne.DragEvent) {
+	if entry.mode == dragUndecided {
+		entry.mode = dragScroll
+		if abs(ev.Dragged.DX) > abs(ev.Dragged.DY) && entry.item != nil && entry.iw.beginDrag(entry.item) {
+			entry.mode = dragItem
+		}
+	}
+	if entry.mode == dragItem {
+		entry.iw.moveDrag(ev.AbsolutePosition)
+	} else if entry.scroll != nil {
+		entry.scroll(ev)
+	}
+}
+
+// DragEnd drops the item on whatever is beneath it.
+func (entry *dragEntry) DragEnd() {
+	if entry.mode == dragItem {
+		entry.iw.endDrag()
+	} else if entry.scrollEnd != nil {
+		entry.scrollEnd()
+	}
+	entry.mode = dragUndecided
+}
+
+// dropTarget is somewhere an item can be dropped, along with the tag to move it into.
+type dropTarget struct {
+	object fyne.CanvasObject
+	tag    int32
+}
+
+// makeDropZone creates a zone that is shown while dragging for moving items somewhere that is not otherwise visible, such as the ground.
+func (iw *InventoryWidget) makeDropZone(text string, tag int32) *fyne.Container {
+	label := widget.NewLabel(text)
+	label.Alignment = fyne.TextAlignCenter
+	zone := container.NewStack(canvas.NewRectangle(dropZoneColor), label)
+	iw.dropZoneTargets = append(iw.dropZoneTargets, dropTarget{object: zone, tag: tag})
+	return zone
+}
+
+// beginDrag starts dragging the given item. It returns false if items cannot be dragged.
+func (iw *InventoryWidget) beginDrag(item *Item) bool {
+	if iw.minimal {
+		return false
+	}
+	iw.dragging = item
+
+	var icon fyne.Resource = data.GetResource("blank.png")
+	if face, ok := data.GetFace(int(item.Face)); ok {
+		icon = face
+	}
+	iw.dragIcon.Resource = icon
+	size := float32(data.CurrentFaceSet().Width)
+	iw.dragIcon.Resize(fyne.NewSize(size, size))
+	iw.dragIcon.Refresh()
+	iw.dragIcon.Show()
+
+	// Only show zones for places other than where the item already is.
+	for i, target := range iw.dropZoneTargets {
+		if target.tag == item.Location || target.tag == iw.inv.Item.Tag {
+			iw.dropZones.Objects[i].Hide()
+		} else {
+			iw.dropZones.Objects[i].Show()
+		}
+	}
+	iw.dropZones.Show()
+	return true
+}
+
+// moveDrag moves the dragged item's icon to follow the pointer.
+func (iw *InventoryWidget) moveDrag(pos fyne.Position) {
+	iw.dragPosition = pos
+	origin := fyne.CurrentApp().Driver().AbsolutePositionForObject(iw.dragLayer)
+	size := iw.dragIcon.Size()
+	iw.dragIcon.Move(pos.Subtract(origin).Subtract(fyne.NewPos(size.Width/2, size.Height/2)))
+}
+
+// endDrag moves the dragged item into the target beneath the pointer, if any.
+func (iw *InventoryWidget) endDrag() {
+	item := iw.dragging
+	iw.dragging = nil
+	iw.dragIcon.Hide()
+	iw.dropZones.Hide()
+	if item == nil {
+		return
+	}
+	if iw.containersStale {
+		defer iw.refreshContainers()
+	}
+
+	targets := append([]dropTarget{{object: iw.itemList, tag: iw.inv.Item.Tag}}, iw.containerTargets...)
+	targets = append(targets, iw.dropZoneTargets...)
+	// Later targets can be atop earlier ones, so check them first.
+	for i := len(targets) - 1; i >= 0; i-- {
+		target := targets[i]
+		if !target.object.Visible() || !iw.contains(target.object, iw.dragPosition) {
+			continue
+		}
+		if target.tag != item.Location && target.tag != item.Tag {
+			iw.moveItem(item, target.tag)
+		}
+		return
+	}
+}
+
+// contains returns if the given absolute position is within the object.
+func (iw *InventoryWidget) contains(obj fyne.CanvasObject, pos fyne.Position) bool {
+	origin := fyne.CurrentApp().Driver().AbsolutePositionForObject(obj)
+	size := obj.Size()
+	return pos.X >= origin.X && pos.Y >= origin.Y && pos.X < origin.X+size.Width && pos.Y < origin.Y+size.Height
+}
+
+// moveItem moves the item into the given tag, asking how many to move if it is a stack.
+func (iw *InventoryWidget) moveItem(item *Item, to int32) {
+	if item.Nrof <= 1 {
+		iw.conn.Send(&messages.MessageMove{
+			To:   to,
+			Tag:  item.Tag,
+			Nrof: 0, // all
+		})
+		return
+	}
+	iw.promptCount("Move Item", "Move", item, func(count int32) {
+		iw.conn.Send(&messages.MessageMove{
+			To:   to,
+			Tag:  item.Tag,
+			Nrof: count,
+		})
+	})
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
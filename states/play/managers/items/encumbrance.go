@@ -0,0 +1,63 @@
+package items
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+// formatWeight formats an item's weight, showing how the total is made up for stacks.
+func formatWeight(item *Item) string {
+	if item.Nrof > 1 {
+		return fmt.Sprintf("%.2fkg (%d×%.2f)", float64(item.StackWeight())/1000, item.Nrof, float64(item.Weight)/1000)
+	}
+	return fmt.Sprintf("%.2fkg", float64(item.StackWeight())/1000)
+}
+
+// totalWeight returns the weight in grams of the inventory. The player's weight is sent by the server, while anything else is the sum of its items.
+func (inv *Inventory) totalWeight() int32 {
+	if inv.kind() == "player" && inv.Item.Weight > 0 {
+		return inv.Item.Weight
+	}
+	var weight int32
+	for _, item := range inv.store.Children(inv.Item.Tag) {
+		weight += item.StackWeight()
+	}
+	return weight
+}
+
+// totalValue returns the value in silver of the inventory's examined items, along with how many items have not been examined.
+func (inv *Inventory) totalValue() (value int64, unknown int) {
+	for _, item := range inv.store.Children(inv.Item.Tag) {
+		if v, ok := inv.mgr.itemValue(item); ok {
+			value += v
+		} else {
+			unknown++
+		}
+	}
+	return value, unknown
+}
+
+// refreshSummary updates the weight and value summary. The carrying limit is only shown for the player's inventory, warning if it has been exceeded.
+func (iw *InventoryWidget) refreshSummary() {
+	inv := iw.inv
+	weight := inv.totalWeight()
+	text := fmt.Sprintf("%.2fkg", float64(weight)/1000)
+	iw.summary.Importance = widget.MediumImportance
+	if limit := inv.mgr.weightLimit; inv.kind() == "player" && limit > 0 {
+		text += fmt.Sprintf(" / %.2fkg", float64(limit)/1000)
+		if weight > limit {
+			text += " over-encumbered!"
+			iw.summary.Importance = widget.DangerImportance
+		}
+	}
+
+	value, unknown := inv.totalValue()
+	if value > 0 {
+		text += fmt.Sprintf(", worth %d silver", value)
+		if unknown > 0 {
+			text += fmt.Sprintf(" (%d unexamined)", unknown)
+		}
+	}
+	iw.summary.SetText(text)
+}
@@ -0,0 +1,282 @@
+package items
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kettek/mobifire/net"
+	"github.com/kettek/termfire/messages"
+)
+
+// examineQuiet is how long to wait after the last line of an examination before considering it complete, as the server does not mark the end of one.
+const examineQuiet = 250 * time.Millisecond
+
+// examineTimeout is how long to wait for the first line of an examination before giving up on it.
+const examineTimeout = 2 * time.Second
+
+// ItemStat is a single stat parsed from an item's examination, such as its damage or a resistance.
+type ItemStat struct {
+	Name  string
+	Value float64
+}
+
+// Examination is the result of examining an item, with common fields parsed out of its text.
+type Examination struct {
+	Tag      int32
+	Text     string
+	Stats    []ItemStat // Stats in the order they were found.
+	Capacity float64    // The weight limit in kg, if the item is a container.
+	Value    int64      // The price in silver, if one was given.
+	HasValue bool
+}
+
+// Stat returns the value of the named stat, if it was found.
+func (ex *Examination) Stat(name string) (float64, bool) {
+	for _, stat := range ex.Stats {
+		if stat.Name == name {
+			return stat.Value, true
+		}
+	}
+	return 0, false
+}
+
+var (
+	examineGroupRegexp  = regexp.MustCompile(`\(([^()]+)\)`)
+	examineStatRegexp   = regexp.MustCompile(`^(dam|wc|ac|armour|speed|weapon speed|Str|Dex|Con|Wis|Cha|Int|Pow|hp|sp|grace|luck|regen)\s*([+-]?[\d.]+)%?$`)
+	examineResistRegexp = regexp.MustCompile(`^(?:resist )?([a-z][a-z ]*?) ([+-]\d+)%?$`)
+	examineWeightRegexp = regexp.MustCompile(`weighs? ([\d.]+) ?kg`)
+)
+
+// examineStatNames maps the short names used in examine text to those shown to the user.
+var examineStatNames = map[string]string{
+	"dam":    "damage",
+	"wc":     "wc",
+	"ac":     "ac",
+	"armour": "armour",
+	"speed":  "speed",
+}
+
+// parseExamination parses the stats, weight, value, and capacity out of the examine text of an item.
+func parseExamination(tag int32, lines []string) *Examination {
+	ex := &Examination{
+		Tag:  tag,
+		Text: strings.Join(lines, "\n"),
+	}
+	add := func(name string, value float64) {
+		if _, ok := ex.Stat(name); !ok {
+			ex.Stats = append(ex.Stats, ItemStat{Name: name, Value: value})
+		}
+	}
+	for _, line := range lines {
+		for _, group := range examineGroupRegexp.FindAllStringSubmatch(line, -1) {
+			text := strings.TrimSpace(group[1])
+			if match := examineStatRegexp.FindStringSubmatch(text); match != nil {
+				value, _ := strconv.ParseFloat(match[2], 64)
+				name := match[1]
+				if short, ok := examineStatNames[name]; ok {
+					name = short
+				}
+				add(name, value)
+			} else if match := examineResistRegexp.FindStringSubmatch(text); match != nil {
+				value, _ := strconv.ParseFloat(match[2], 64)
+				add("resist "+match[1], value)
+			}
+		}
+		if match := examineWeightRegexp.FindStringSubmatch(line); match != nil {
+			if kg, err := strconv.ParseFloat(match[1], 64); err == nil {
+				add("weight", kg)
+			}
+		}
+		if capacity, ok := parseCapacity(line); ok {
+			ex.Capacity = capacity
+		}
+		if value, ok := parseValue(line); ok {
+			ex.Value = value
+			ex.HasValue = true
+			add("value", float64(value))
+		}
+	}
+	return ex
+}
+
+// examineHeader starts the server's reply to an examine request, followed by the name of the item.
+const examineHeader = "You examine the "
+
+// examiner examines items one at a time so that the server's replies can be attributed to the item they are for, caching the results by tag.
+type examiner struct {
+	sync.Mutex
+	conn    *net.Connection
+	lookup  func(tag int32) *Item
+	queue   []int32
+	current int32 // The tag being examined, or 0 if none.
+	lines   []string
+	foreign bool // Whether the reply being received is for some other item, such as one examined by the user.
+	retried bool // Whether the current examination has been resent after the server asked to examine again.
+	timer   *time.Timer
+	cache   map[int32]*Examination
+	done    []*Examination // Examinations completed since last taken, to be handled on the goroutine that owns the items.
+
+	capacities map[int32]float64 // Container weight limits, which are kept when examinations are invalidated as they never change.
+}
+
+func newExaminer(conn *net.Connection, lookup func(tag int32) *Item) *examiner {
+	return &examiner{
+		conn:       conn,
+		lookup:     lookup,
+		cache:      make(map[int32]*Examination),
+		capacities: make(map[int32]float64),
+	}
+}
+
+// Get returns the cached examination of the given tag, if any.
+func (e *examiner) Get(tag int32) *Examination {
+	e.Lock()
+	defer e.Unlock()
+	return e.cache[tag]
+}
+
+// Capacity returns the weight limit in kg of the given container, if it has been examined.
+func (e *examiner) Capacity(tag int32) (float64, bool) {
+	e.Lock()
+	defer e.Unlock()
+	capacity, ok := e.capacities[tag]
+	return capacity, ok
+}
+
+// Examine requests an examination of the given tag unless one is cached or already pending.
+func (e *examiner) Examine(tag int32) {
+	e.Lock()
+	defer e.Unlock()
+	if _, ok := e.cache[tag]; ok || e.current == tag {
+		return
+	}
+	for _, queued := range e.queue {
+		if queued == tag {
+			return
+		}
+	}
+	e.queue = append(e.queue, tag)
+	if e.current == 0 {
+		e.next()
+	}
+}
+
+// Invalidate drops the cached examination of the given tag, such as when the item changes.
+func (e *examiner) Invalidate(tag int32) {
+	e.Lock()
+	defer e.Unlock()
+	delete(e.cache, tag)
+}
+
+// next starts examining the next queued tag. It must be called with the lock held.
+func (e *examiner) next() {
+	if len(e.queue) == 0 {
+		e.current = 0
+		return
+	}
+	e.current = e.queue[0]
+	e.queue = e.queue[1:]
+	e.lines = nil
+	e.foreign = false
+	e.retried = false
+	e.conn.Send(&messages.MessageExamine{
+		Tag: e.current,
+	})
+	e.wait(examineTimeout)
+}
+
+// wait (re)starts the timer for completing the current examination. It must be called with the lock held.
+func (e *examiner) wait(d time.Duration) {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	tag := e.current
+	e.timer = time.AfterFunc(d, func() {
+		e.complete(tag)
+	})
+}
+
+// complete finishes the examination of the given tag, if it is still the current one, and starts on the next. It is called from the timer, so it only records the examination as done, leaving it to be taken by Completed.
+func (e *examiner) complete(tag int32) {
+	e.Lock()
+	defer e.Unlock()
+	if e.current != tag {
+		return
+	}
+	if len(e.lines) > 0 {
+		ex := parseExamination(tag, e.lines)
+		e.cache[tag] = ex
+		if ex.Capacity > 0 {
+			e.capacities[tag] = ex.Capacity
+		}
+		e.done = append(e.done, ex)
+	}
+	e.next()
+}
+
+// Completed takes the examinations that have completed since it was last called.
+func (e *examiner) Completed() []*Examination {
+	e.Lock()
+	defer e.Unlock()
+	done := e.done
+	e.done = nil
+	return done
+}
+
+// handle collects a line of examine text for the current examination.
+func (e *examiner) handle(msg *messages.MessageDrawExtInfo) {
+	if !(msg.Type == messages.MessageTypeCommand && msg.Subtype == messages.SubMessageTypeCommandExamine) && !(msg.Type == messages.MessageTypeSpell && msg.Subtype == messages.SubMessageTypeSpellInfo) {
+		return
+	}
+	e.Lock()
+	defer e.Unlock()
+	if e.current == 0 {
+		return
+	}
+	// The server asks for another examine when the item was not yet identified well enough. Resending more than once could loop forever, so a second request is left to time out.
+	if strings.HasPrefix(msg.Message, "Examine again") {
+		if e.retried {
+			return
+		}
+		e.retried = true
+		e.lines = nil
+		e.conn.Send(&messages.MessageExamine{
+			Tag: e.current,
+		})
+		e.wait(examineTimeout)
+		return
+	}
+	// Each reply starts with a header naming its item, so replies for anything else are skipped until the next header.
+	if name, ok := strings.CutPrefix(msg.Message, examineHeader); ok {
+		e.foreign = !e.matches(name)
+		if !e.foreign {
+			e.lines = nil
+			e.wait(examineQuiet)
+		}
+		return
+	}
+	if e.foreign {
+		return
+	}
+	e.lines = append(e.lines, msg.Message)
+	e.wait(examineQuiet)
+}
+
+// matches returns if an examine header's name is that of the item being examined. The server may name a stack by its count and plural, so either name is accepted. It must be called with the lock held.
+func (e *examiner) matches(name string) bool {
+	item := e.lookup(e.current)
+	if item == nil {
+		// Without the item, there is nothing to tell the reply apart by.
+		return true
+	}
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	for _, own := range []string{item.Name, item.PluralName} {
+		if own != "" && strings.Contains(name, strings.ToLower(own)) {
+			return true
+		}
+	}
+	return false
+}
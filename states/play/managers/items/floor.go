@@ -0,0 +1,84 @@
+package items
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/data"
+	"github.com/kettek/mobifire/states/play/cfwidgets"
+)
+
+// FloorPanel is a collapsible strip of the items on the ground beneath the player. Tapping an item picks it up and long-pressing it examines it.
+type FloorPanel struct {
+	inv       *Inventory
+	toggle    *widget.Button
+	items     *fyne.Container
+	scroll    *container.Scroll
+	container *fyne.Container
+	collapsed bool
+
+	onPickup      func(item *Item)
+	onExamine     func(item *Item)
+	onCollapseSet func(collapsed bool)
+}
+
+func newFloorPanel(inv *Inventory, collapsed bool) *FloorPanel {
+	panel := &FloorPanel{
+		inv:       inv,
+		collapsed: collapsed,
+	}
+	panel.toggle = widget.NewButtonWithIcon("", data.GetResource("icon_pickup.png"), func() {
+		panel.SetCollapsed(!panel.collapsed)
+	})
+	panel.items = container.NewHBox()
+	panel.scroll = container.NewHScroll(panel.items)
+	panel.container = container.NewBorder(nil, nil, panel.toggle, nil, panel.scroll)
+	panel.Refresh()
+	return panel
+}
+
+// SetCollapsed sets whether the items are hidden, leaving only the toggle and item count.
+func (panel *FloorPanel) SetCollapsed(collapsed bool) {
+	panel.collapsed = collapsed
+	if panel.onCollapseSet != nil {
+		panel.onCollapseSet(collapsed)
+	}
+	panel.Refresh()
+}
+
+// Refresh rebuilds the strip from the ground's items. Every item is shown, regardless of any search or filters left in the ground's popup.
+func (panel *FloorPanel) Refresh() {
+	items := panel.inv.store.Children(panel.inv.Item.Tag)
+	panel.toggle.SetText(fmt.Sprintf("%d", len(items)))
+
+	if panel.collapsed {
+		panel.scroll.Hide()
+	} else {
+		panel.scroll.Show()
+	}
+
+	panel.items.RemoveAll()
+	for _, item := range items {
+		var icon fyne.Resource = data.GetResource("blank.png")
+		if face, ok := data.GetFace(int(item.Face)); ok {
+			icon = face
+		}
+		panel.items.Add(cfwidgets.NewAssignableButton(icon, func() {
+			if panel.onPickup != nil {
+				panel.onPickup(item)
+			}
+		}, func() {
+			if panel.onExamine != nil {
+				panel.onExamine(item)
+			}
+		}))
+	}
+	panel.container.Refresh()
+}
+
+// Container returns the panel's container.
+func (panel *FloorPanel) Container() *fyne.Container {
+	return panel.container
+}
@@ -2,10 +2,21 @@ package items
 
 import "github.com/kettek/termfire/messages"
 
-// Item is a wrapper around ItemObject but with extra examine info.
+// Item is a wrapper around ItemObject along with where it is.
 type Item struct {
 	messages.ItemObject
-	examineInfo string
+	Location int32 // The tag of what contains the item, or 0 if it is on the ground.
+}
+
+// StackWeight returns the weight in grams of the whole stack. The server sends the weight of a single item, so it is multiplied by the count.
+func (item *Item) StackWeight() int32 {
+	if item.Weight < 0 {
+		return 0 // Unpickable items have no weight.
+	}
+	if item.Nrof > 1 {
+		return item.Weight * item.Nrof
+	}
+	return item.Weight
 }
 
 // Update updates the item to match the update item message.
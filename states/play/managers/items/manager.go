@@ -1,6 +1,9 @@
 package items
 
 import (
+	"slices"
+	"sync"
+
 	"fyne.io/fyne/v2"
 	"github.com/kettek/mobifire/net"
 	"github.com/kettek/termfire/messages"
@@ -8,12 +11,31 @@ import (
 
 // Manager provides functionality for managing items and inventories. This handles network messages pertaining to items as well as the displaying of dialogs for given inventories.
 type Manager struct {
+	app     fyne.App
 	window  fyne.Window
 	conn    *net.Connection
 	handler *messages.MessageHandler
 
-	inventories []*Inventory
+	store       *Store
+	changed     map[int32]bool       // Tags changed while handling the current message, refreshed once it has been handled.
+	inventories map[int32]*Inventory // Views over the store, by the tag of what contains their items.
 	playerTag   int32
+	playerName  string
+	weightLimit int32 // The most the player can carry in grams, as per the weight limit stat.
+
+	pickupMode   uint32 // The pickup mode, as last reported by the server.
+	pickupKnown  bool
+	onPickupMode func(mode uint32)
+
+	lootRules   []lootRule // The current character's loot rules.
+	lootEnabled bool
+	looter      looter
+
+	prices     map[string]int64 // Unit prices of items that have been seen unpaid, by archKey.
+	pricesLock sync.Mutex       // Prices are cached as examinations complete, but read while drawing.
+
+	examiner *examiner
+	doll     *paperDoll // The equipment view, if it has been shown.
 }
 
 // NewManager creates a new items/inventory manager.
@@ -21,6 +43,11 @@ func NewManager() *Manager {
 	return &Manager{}
 }
 
+// SetApp sets the app for the manager.
+func (mgr *Manager) SetApp(app fyne.App) {
+	mgr.app = app
+}
+
 // SetManager sets the managers for the manager.
 func (mgr *Manager) SetWindow(w fyne.Window) {
 	mgr.window = w
@@ -36,8 +63,21 @@ func (mgr *Manager) SetHandler(h *messages.MessageHandler) {
 	mgr.handler = h
 }
 
-// Init sets up broad message handling for inventories, such as making them, deleting them, or transferring items between inventories. Further message response functionality is defined in the Inventory type itself.
+// Init sets up message handling for items. All items are kept in a single store, and inventories are views over it that are synced whenever their contents change.
 func (mgr *Manager) Init() {
+	mgr.store = NewStore()
+	mgr.changed = make(map[int32]bool)
+	mgr.inventories = make(map[int32]*Inventory)
+	mgr.store.OnChange(func(tag int32) {
+		// Examinations are out of date once an item changes.
+		mgr.examiner.Invalidate(tag)
+		mgr.changed[tag] = true
+	})
+
+	mgr.loadPrices()
+	mgr.examiner = newExaminer(mgr.conn, mgr.store.Get)
+	mgr.store.OnArrive(mgr.applyLootRules)
+
 	mgr.handler.On(&messages.MessagePlayer{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
 		// We also handle player message, as this gives us the player's name and tag.
 		msg := m.(*messages.MessagePlayer)
@@ -45,129 +85,238 @@ func (mgr *Manager) Init() {
 			return
 		}
 		mgr.playerTag = msg.Tag
-		inv, _ := mgr.ensureInventory(msg.Tag)
+		mgr.playerName = msg.Name
+		mgr.loadLootRules()
+		inv := mgr.ensureInventory(msg.Tag)
 		inv.Item.Name = msg.Name + "'s Inventory" // TODO: Maybe set a field to denote player inventory and determine the title on popup.
 		inv.Item.Weight = msg.Weight
 		inv.Item.TotalWeight = msg.Weight
+		inv.refreshViews()
+	})
+	// Examinations complete on a timer, so they are handled on the next message instead, where they can't race with changes to the items. Ticks keep this timely.
+	tick := messages.MessageTick(0)
+	mgr.handler.On(&tick, nil, func(m messages.Message, mf *messages.MessageFailure) {
+		mgr.handleExaminations()
+	})
+	mgr.handler.On(&messages.MessageStats{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
+		mgr.handleExaminations()
+		msg := m.(*messages.MessageStats)
+		for _, stat := range msg.Stats {
+			switch stat := stat.(type) {
+			case *messages.MessageStatWeightLim:
+				mgr.weightLimit = int32(*stat)
+				if inv, ok := mgr.inventories[mgr.playerTag]; ok && inv.widget != nil {
+					inv.widget.refreshSummary()
+				}
+			}
+		}
 	})
 	mgr.handler.On(&messages.MessageItem2{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
 		msg := m.(*messages.MessageItem2)
-		inv, existed := mgr.ensureInventory(msg.Location)
-		// Send the inventory the item2 command if it's only just created, as it'll miss the message otherwise.
-		if !existed {
-			inv.handleItem2(msg)
+		for _, o := range msg.Objects {
+			mgr.store.Add(msg.Location, o)
 		}
+		mgr.refreshChanged()
 	})
 	mgr.handler.On(&messages.MessageDeleteItem{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
 		msg := m.(*messages.MessageDeleteItem)
-		// Remove any inventories that match the item.
 		for _, tag := range msg.Tags {
-			mgr.removeInventory(tag)
+			mgr.store.Delete(tag)
+			mgr.examiner.Invalidate(tag)
+			mgr.changed[tag] = true
+			// Remove any inventories that match the item.
+			delete(mgr.inventories, tag)
 		}
+		mgr.refreshChanged()
+	})
+	mgr.handler.On(&messages.MessageDeleteInventory{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
+		msg := m.(*messages.MessageDeleteInventory)
+		mgr.store.Clear(msg.Tag)
+		mgr.refreshChanged()
 	})
 	mgr.handler.On(&messages.MessageUpdateItem{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
 		msg := m.(*messages.MessageUpdateItem)
-		// This feels gross, but if the item got a location update, we need to find it and move it to the appropriate inventory. This would be less iffy with a global objects design, but I've been preferring this inventory-centric approach...
-		for _, mf := range msg.Fields {
-			switch f := mf.(type) {
-			case messages.MessageUpdateItemLocation:
-				targetInv, _ := mgr.ensureInventory(int32(f))
-				for _, inv := range mgr.inventories {
-					if item := inv.getItemByTag(msg.Tag); item != nil {
-						targetInv.addItem(item)
-						targetInv.sortItems()
-						inv.removeItemByTag(msg.Tag)
-						break
-					}
-				}
-			}
+		if mgr.store.Update(msg) {
+			mgr.refreshChanged()
+			return
+		}
+		// The player is not an item in the store, but still gets updates, such as to its weight.
+		if inv, ok := mgr.inventories[msg.Tag]; ok {
+			inv.Item.Update(msg)
+			inv.refreshViews()
 		}
 	})
+	mgr.handler.On(&messages.MessageDrawExtInfo{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
+		msg := m.(*messages.MessageDrawExtInfo)
+		mgr.examiner.handle(msg)
+		mgr.handleExaminations()
+		mgr.handlePickupInfo(msg)
+	})
 }
 
-func (mgr *Manager) ensureInventory(tag int32) (*Inventory, bool) {
+// refreshChanged syncs the inventories whose items changed while handling a message, and refreshes the parts of inventory widgets the changes affect. This is done once per message, as an item2 message may add many items.
+func (mgr *Manager) refreshChanged() {
+	if len(mgr.changed) == 0 {
+		return
+	}
+	changed := mgr.changed
+	mgr.changed = make(map[int32]bool)
+
+	for tag := range changed {
+		if inv, ok := mgr.inventories[tag]; ok {
+			inv.sync()
+		}
+	}
+	shop := false
+	for tag := range changed {
+		if mgr.affectsShop(tag) {
+			shop = true
+			break
+		}
+	}
 	for _, inv := range mgr.inventories {
-		if inv.Item.Tag == tag {
-			return inv, true
+		if inv.widget == nil {
+			continue
+		}
+		for tag := range changed {
+			if inv.widget.affectsContainers(tag) {
+				inv.widget.refreshContainers()
+				break
+			}
+		}
+		if shop {
+			inv.widget.refreshShop()
 		}
 	}
-	inv := newInventory(tag)
-	inv.setup(mgr.handler, mgr.conn)
-	mgr.inventories = append(mgr.inventories, inv)
-	return inv, false
+	if mgr.doll != nil && changed[mgr.playerTag] {
+		mgr.doll.refresh()
+	}
 }
 
-func (mgr *Manager) removeInventory(tag int32) {
-	for i, inv := range mgr.inventories {
-		if inv.Item.Tag == tag {
-			mgr.inventories = append(mgr.inventories[:i], mgr.inventories[i+1:]...)
-			inv.cleanup(mgr.handler)
-			return
+// handleExaminations caches the prices of newly examined items and refreshes any widget showing them or values that depend on examinations. It must be called from the message handler, as it reads the inventories.
+func (mgr *Manager) handleExaminations() {
+	done := mgr.examiner.Completed()
+	if len(done) == 0 {
+		return
+	}
+	for _, ex := range done {
+		if item := mgr.store.Get(ex.Tag); item != nil && item.Flags.Unpaid() && ex.HasValue {
+			mgr.cachePrice(item, ex.Value)
+		}
+	}
+	for _, inv := range mgr.inventories {
+		if inv.widget == nil {
+			continue
+		}
+		for _, ex := range done {
+			if inv.widget.selectedTag() == ex.Tag || inv.widget.compareTag == ex.Tag {
+				inv.widget.refreshExamination()
+				break
+			}
 		}
+		inv.widget.itemList.Refresh()
+		inv.widget.refreshContainers()
+		inv.widget.refreshSummary()
+		inv.widget.refreshShop()
+	}
+}
+
+// itemValue returns the value in silver of the item, if it is known from examining it.
+func (mgr *Manager) itemValue(item *Item) (int64, bool) {
+	if ex := mgr.examiner.Get(item.Tag); ex != nil && ex.HasValue {
+		return ex.Value, true
 	}
+	return 0, false
+}
+
+// ensureInventory returns the inventory view for the given tag, creating it if needed.
+func (mgr *Manager) ensureInventory(tag int32) *Inventory {
+	if inv, ok := mgr.inventories[tag]; ok {
+		return inv
+	}
+	inv := newInventory(tag, mgr)
+	mgr.inventories[tag] = inv
+	return inv
 }
 
 // ShowInventory shows an inventory by its tag.
 func (mgr *Manager) ShowInventory(tag int32, onSelect func(item *Item) bool) {
-	for _, inv := range mgr.inventories {
-		if inv.Item.Tag == tag {
-			inv.onSelect = onSelect
-			inv.showPopup(mgr.window, mgr.conn, false)
-			return
-		}
-	}
+	inv := mgr.ensureInventory(tag)
+	inv.onSelect = onSelect
+	inv.showPopup(mgr.window, mgr.conn, false)
 }
 
 // ShowLimitedInventory shows an inventory with limited options.
 func (mgr *Manager) ShowLimitedInventory(tag int32, onSelect func(item *Item) bool) {
-	for _, inv := range mgr.inventories {
-		if inv.Item.Tag == tag {
-			inv.onSelect = onSelect
-			inv.showPopup(mgr.window, mgr.conn, true)
-			return
-		}
-	}
+	inv := mgr.ensureInventory(tag)
+	inv.onSelect = onSelect
+	inv.showPopup(mgr.window, mgr.conn, true)
 }
 
 func (mgr *Manager) GetInventoryPanel(tag int32) *InventoryPanel {
-	inv, _ := mgr.ensureInventory(tag)
+	inv := mgr.ensureInventory(tag)
 	if inv.panel == nil {
 		inv.panel = newInventoryPanel(inv, mgr.window, mgr.conn)
 	}
 	return inv.panel
 }
 
+// GetFloorPanel returns the panel of items on the ground beneath the player, which the server sends as the inventory at location 0.
+func (mgr *Manager) GetFloorPanel() *FloorPanel {
+	inv := mgr.ensureInventory(0)
+	if inv.floor == nil {
+		inv.Item.Name = "Ground"
+		inv.floor = newFloorPanel(inv, mgr.app.Preferences().Bool("floorCollapsed"))
+		inv.floor.onCollapseSet = func(collapsed bool) {
+			mgr.app.Preferences().SetBool("floorCollapsed", collapsed)
+		}
+		inv.floor.onPickup = func(item *Item) {
+			mgr.conn.Send(&messages.MessageMove{
+				To:   mgr.playerTag,
+				Tag:  item.Tag,
+				Nrof: 0, // all
+			})
+		}
+		inv.floor.onExamine = func(item *Item) {
+			inv.onSelect = nil
+			inv.showPopup(mgr.window, mgr.conn, false)
+			// The item may be hidden by the popup's search or filters, in which case they are left for the user to clear.
+			if index := slices.Index(inv.Items, item); index != -1 {
+				inv.widget.itemList.Select(index)
+			}
+		}
+	}
+	return inv.floor
+}
+
 // CloseInventory closes an inventory by its tag.
 func (mgr *Manager) CloseInventory(tag int32) {
-	for _, inv := range mgr.inventories {
-		if inv.Item.Tag == tag {
-			inv.closePopup()
-			return
-		}
+	if inv, ok := mgr.inventories[tag]; ok {
+		inv.closePopup()
 	}
 }
 
 // GetItemByTag returns an item by its tag.
 func (mgr *Manager) GetItemByTag(tag int32) *Item {
-	for _, inv := range mgr.inventories {
-		if item := inv.getItemByTag(tag); item != nil {
-			return item
-		}
-	}
-	return nil
+	return mgr.store.Get(tag)
 }
 
 // GetItemByName returns an item by its name.
 func (mgr *Manager) GetItemByName(name string) *Item {
-	for _, inv := range mgr.inventories {
-		if item := inv.getItemByName(name); item != nil {
-			return item
-		}
-	}
-	return nil
+	return mgr.store.ByName(name)
 }
 
 // GetPlayerTag returns the tag of the player.
 func (mgr *Manager) GetPlayerTag() int32 {
 	return mgr.playerTag
 }
+
+// GetGroundItemByFace returns an item on the ground beneath the player that is drawn with the given face, or nil if there is none.
+func (mgr *Manager) GetGroundItemByFace(face int32) *Item {
+	for _, item := range mgr.store.Children(0) {
+		if item.Face == face {
+			return item
+		}
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package items
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/states/play/cfwidgets"
+	"github.com/kettek/mobifire/states/play/layouts"
+	"github.com/kettek/termfire/messages"
+)
+
+// Pickup mode bits, as per the server's pickup command.
+const (
+	pickupRatio        uint32 = 0x0000000F // Value to weight ratio, in steps of 5.
+	pickupFood         uint32 = 0x00000010
+	pickupDrink        uint32 = 0x00000020
+	pickupValuables    uint32 = 0x00000040
+	pickupBow          uint32 = 0x00000080
+	pickupArrow        uint32 = 0x00000100
+	pickupHelmet       uint32 = 0x00000200
+	pickupShield       uint32 = 0x00000400
+	pickupArmour       uint32 = 0x00000800
+	pickupBoots        uint32 = 0x00001000
+	pickupGloves       uint32 = 0x00002000
+	pickupCloak        uint32 = 0x00004000
+	pickupKey          uint32 = 0x00008000
+	pickupMissile      uint32 = 0x00010000
+	pickupMelee        uint32 = 0x00020000
+	pickupMagical      uint32 = 0x00040000
+	pickupPotion       uint32 = 0x00080000
+	pickupSpellbook    uint32 = 0x00100000
+	pickupSkillscroll  uint32 = 0x00200000
+	pickupReadables    uint32 = 0x00400000
+	pickupMagicDevice  uint32 = 0x00800000
+	pickupNotCursed    uint32 = 0x01000000
+	pickupJewels       uint32 = 0x02000000
+	pickupFlesh        uint32 = 0x04000000
+	pickupContainer    uint32 = 0x08000000
+	pickupDebug        uint32 = 0x10000000
+	pickupInhibit      uint32 = 0x20000000
+	pickupStop         uint32 = 0x40000000
+	pickupNewMode      uint32 = 0x80000000
+	pickupRatioStep           = 5
+	pickupRatioMaximum        = 15
+)
+
+// pickupFlag is a pickup mode bit, along with the name the server reports it by and a label for the dialog.
+type pickupFlag struct {
+	bit    uint32
+	name   string
+	label  string
+	option bool // Whether the flag changes how pickup works, rather than what is picked up.
+}
+
+var pickupFlags = []pickupFlag{
+	{pickupInhibit, "INHIBIT", "disable pickup", true},
+	{pickupStop, "STOP", "stop when picking up", true},
+	{pickupNotCursed, "NOT CURSED", "skip known cursed", true},
+	{pickupDebug, "DEBUG", "report mode changes", true},
+	{pickupFood, "FOOD", "food", false},
+	{pickupDrink, "DRINK", "drinks", false},
+	{pickupFlesh, "FLESH", "flesh", false},
+	{pickupPotion, "POTION", "potions", false},
+	{pickupValuables, "VALUABLES", "money and gems", false},
+	{pickupJewels, "JEWELS", "rings and amulets", false},
+	{pickupMagical, "MAGICAL", "magical items", false},
+	{pickupKey, "KEY", "keys", false},
+	{pickupContainer, "CONTAINER", "containers", false},
+	{pickupSpellbook, "SPELLBOOK", "spellbooks", false},
+	{pickupSkillscroll, "SKILLSCROLL", "skill scrolls", false},
+	{pickupReadables, "READABLES", "readables", false},
+	{pickupMagicDevice, "MAGIC_DEVICE", "wands, rods and horns", false},
+	{pickupMelee, "MELEEWEAPON", "melee weapons", false},
+	{pickupMissile, "MISSILEWEAPON", "missile weapons", false},
+	{pickupBow, "BOW", "bows", false},
+	{pickupArrow, "ARROW", "arrows", false},
+	{pickupHelmet, "HELMET", "helmets", false},
+	{pickupShield, "SHIELD", "shields", false},
+	{pickupArmour, "ARMOUR", "armour", false},
+	{pickupBoots, "BOOTS", "boots", false},
+	{pickupGloves, "GLOVES", "gloves", false},
+	{pickupCloak, "CLOAK", "cloaks", false},
+}
+
+var (
+	pickupFlagRegexp  = regexp.MustCompile(`^\s*([01]) ([A-Z][A-Z_ ]*?)\s*$`)
+	pickupRatioRegexp = regexp.MustCompile(`^\s*(\d+) <= x pickup weight/value RATIO`)
+)
+
+// parsePickupLine updates the pickup mode from a line of the server's pickup report. The report lists each flag on its own line, and is only sent when the debug flag is set. It returns false if the line is not part of a report.
+func parsePickupLine(mode uint32, line string) (uint32, bool) {
+	if strings.HasPrefix(line, "Pickup is now inhibited") {
+		return mode | pickupInhibit, true
+	} else if strings.HasPrefix(line, "Pickup is now activated") {
+		return mode &^ pickupInhibit, true
+	}
+	if match := pickupRatioRegexp.FindStringSubmatch(line); match != nil {
+		ratio, _ := strconv.Atoi(match[1])
+		return mode&^pickupRatio | uint32(ratio/pickupRatioStep)&pickupRatio, true
+	}
+	match := pickupFlagRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return mode, false
+	}
+	if match[2] == "NEWMODE" {
+		return mode | pickupNewMode, true
+	}
+	name := strings.ReplaceAll(match[2], " ", "_")
+	for _, flag := range pickupFlags {
+		if strings.ReplaceAll(flag.name, " ", "_") != name {
+			continue
+		}
+		if match[1] == "1" {
+			return mode | flag.bit, true
+		}
+		return mode &^ flag.bit, true
+	}
+	return mode, false
+}
+
+// describePickup returns a short description of the given pickup mode.
+func describePickup(mode uint32) string {
+	if mode&pickupInhibit != 0 {
+		return "disabled"
+	}
+	var parts []string
+	if ratio := mode & pickupRatio; ratio != 0 {
+		parts = append(parts, fmt.Sprintf("value/weight >= %d", ratio*pickupRatioStep))
+	}
+	for _, flag := range pickupFlags {
+		if !flag.option && mode&flag.bit != 0 {
+			parts = append(parts, flag.label)
+		}
+	}
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handlePickupInfo tracks the pickup mode from the server's reports of it.
+func (mgr *Manager) handlePickupInfo(msg *messages.MessageDrawExtInfo) {
+	if msg.Type != messages.MessageTypeCommand {
+		return
+	}
+	mode, ok := parsePickupLine(mgr.pickupMode, msg.Message)
+	if !ok {
+		return
+	}
+	mgr.pickupMode = mode
+	mgr.pickupKnown = true
+	if mgr.onPickupMode != nil {
+		mgr.onPickupMode(mode)
+	}
+}
+
+// pickupPresetsKey returns the preferences key for the current character's pickup presets.
+func (mgr *Manager) pickupPresetsKey() string {
+	return "pickupPresets-" + mgr.playerName
+}
+
+// pickupPresets returns the current character's pickup presets, by name.
+func (mgr *Manager) pickupPresets() map[string]uint32 {
+	presets := make(map[string]uint32)
+	for _, entry := range mgr.app.Preferences().StringList(mgr.pickupPresetsKey()) {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		mode, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			continue
+		}
+		presets[name] = uint32(mode)
+	}
+	return presets
+}
+
+// setPickupPresets saves the current character's pickup presets.
+func (mgr *Manager) setPickupPresets(presets map[string]uint32) {
+	var entries []string
+	for name, mode := range presets {
+		entries = append(entries, fmt.Sprintf("%s=%d", name, mode))
+	}
+	slices.Sort(entries)
+	mgr.app.Preferences().SetStringList(mgr.pickupPresetsKey(), entries)
+}
+
+// ShowPickup shows a dialog for building and sending the pickup mode, as well as saving it as a preset for the current character. The server is asked for the current mode as the dialog opens, and the controls are only set from it once it is known.
+func (mgr *Manager) ShowPickup() {
+	mode := pickupNewMode
+	if mgr.pickupKnown {
+		mode |= mgr.pickupMode
+	}
+	edited := false  // Whether the user has changed the controls, in which case a late report of the mode doesn't replace their changes.
+	seeding := false // Whether the controls are being set from a mode, rather than by the user.
+	var send *widget.Button
+
+	current := widget.NewLabel("")
+	current.Wrapping = fyne.TextWrapWord
+	setCurrent := func(mode uint32) {
+		if mgr.pickupKnown {
+			current.SetText("Current: " + describePickup(mode))
+		} else {
+			current.SetText("Current: asking the server...")
+		}
+	}
+	setCurrent(mgr.pickupMode)
+	setEdited := func() {
+		if seeding {
+			return
+		}
+		edited = true
+		send.Enable()
+	}
+
+	var checks []*widget.Check
+	options := container.NewGridWithColumns(2)
+	types := container.NewGridWithColumns(2)
+	for _, flag := range pickupFlags {
+		check := widget.NewCheck(flag.label, func(checked bool) {
+			if checked {
+				mode |= flag.bit
+			} else {
+				mode &^= flag.bit
+			}
+			setEdited()
+		})
+		checks = append(checks, check)
+		if flag.option {
+			options.Add(check)
+		} else {
+			types.Add(check)
+		}
+	}
+
+	ratioLabel := widget.NewLabel("")
+	setRatioLabel := func(ratio uint32) {
+		if ratio == 0 {
+			ratioLabel.SetText("value/weight: off")
+		} else {
+			ratioLabel.SetText(fmt.Sprintf("value/weight >= %d", ratio*pickupRatioStep))
+		}
+	}
+	ratio := widget.NewSlider(0, pickupRatioMaximum)
+	ratio.OnChanged = func(value float64) {
+		mode = mode&^pickupRatio | uint32(value)&pickupRatio
+		setRatioLabel(uint32(value))
+		setEdited()
+	}
+
+	// setMode updates the controls to match the given mode.
+	setMode := func(m uint32) {
+		seeding = true
+		defer func() { seeding = false }()
+		mode = m | pickupNewMode
+		for i, flag := range pickupFlags {
+			checks[i].SetChecked(mode&flag.bit != 0)
+		}
+		ratio.SetValue(float64(mode & pickupRatio))
+		setRatioLabel(mode & pickupRatio)
+	}
+	setMode(mode)
+
+	presetName := widget.NewSelectEntry(nil)
+	presetName.SetPlaceHolder("preset")
+	refreshPresets := func() {
+		presets := mgr.pickupPresets()
+		var names []string
+		for name := range presets {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		presetName.SetOptions(names)
+	}
+	refreshPresets()
+	presetName.OnChanged = func(name string) {
+		if preset, ok := mgr.pickupPresets()[name]; ok {
+			setMode(preset)
+			setEdited()
+		}
+	}
+	savePreset := widget.NewButton("save", func() {
+		name := strings.TrimSpace(presetName.Text)
+		if name == "" || strings.Contains(name, "=") {
+			return
+		}
+		presets := mgr.pickupPresets()
+		presets[name] = mode
+		mgr.setPickupPresets(presets)
+		refreshPresets()
+	})
+	deletePreset := widget.NewButton("delete", func() {
+		presets := mgr.pickupPresets()
+		delete(presets, presetName.Text)
+		mgr.setPickupPresets(presets)
+		presetName.SetText("")
+		refreshPresets()
+	})
+
+	send = widget.NewButton("send", func() {
+		mgr.conn.SendCommand(fmt.Sprintf("pickup %d", mode), 0)
+	})
+	// Sending an unknown mode would replace the player's with whatever the controls were left at.
+	if !mgr.pickupKnown {
+		send.Disable()
+	}
+
+	mgr.onPickupMode = func(m uint32) {
+		setCurrent(m)
+		if !edited {
+			setMode(m)
+			send.Enable()
+		}
+	}
+	// Without parameters, the server lists the current mode.
+	mgr.conn.SendCommand("pickup", 0)
+
+	dialog := layouts.NewDialog(mgr.window)
+	dialog.Full = true
+
+	content := container.NewBorder(
+		container.NewVBox(current, container.NewBorder(nil, nil, nil, container.NewHBox(savePreset, deletePreset), presetName)),
+		container.NewHBox(layout.NewSpacer(), send),
+		nil,
+		nil,
+		container.NewVScroll(container.NewVBox(options, widget.NewSeparator(), container.NewBorder(nil, nil, ratioLabel, nil, ratio), types)),
+	)
+	popup := cfwidgets.NewPopUp(container.New(dialog, content), mgr.window.Canvas())
+	popup.ShowCentered(mgr.window.Canvas())
+}
@@ -0,0 +1,336 @@
+package items
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/states/play/cfwidgets"
+	"github.com/kettek/mobifire/states/play/layouts"
+	"github.com/kettek/termfire/messages"
+)
+
+// lootInterval is the least time between actions taken by loot rules, so as to not flood the server.
+const lootInterval = 300 * time.Millisecond
+
+// Actions that loot rules can take.
+const (
+	lootPickup = "pickup"
+	lootDrop   = "drop"
+	lootMark   = "mark"
+)
+
+var lootActions = []string{lootPickup, lootDrop, lootMark}
+
+// itemCategories are the kinds of item that loot rules can match against.
+var itemCategories = map[string]func(t messages.ItemType) bool{
+	"container":    messages.ItemType.IsContainer,
+	"drink":        messages.ItemType.IsDrink,
+	"potion":       messages.ItemType.IsPotion,
+	"food":         messages.ItemType.IsFood,
+	"flesh":        messages.ItemType.IsFlesh,
+	"magic device": messages.ItemType.IsSpellCastingConsumable,
+	"readable":     messages.ItemType.IsReadable,
+}
+
+// lootRule matches items on the ground or in the player's inventory and takes an action upon them. Pickup rules only apply to the ground, while drop and mark rules only apply to the inventory.
+type lootRule struct {
+	Enabled   bool
+	Pattern   string   // A case-insensitive glob matched against the item's name, such as "*arrow*". Empty matches everything.
+	Category  string   // One of itemCategories, or empty for any.
+	Flags     []string // Flags, as per itemFilters, that the item must have.
+	MaxWeight int32    // The most the whole stack may weigh in grams, or 0 for any weight.
+	Action    string
+}
+
+// String returns a short description of the rule.
+func (r lootRule) String() string {
+	str := r.Action
+	if r.Pattern != "" {
+		str += " \"" + r.Pattern + "\""
+	} else {
+		str += " anything"
+	}
+	if r.Category != "" {
+		str += " (" + r.Category + ")"
+	}
+	if len(r.Flags) > 0 {
+		str += " [" + strings.Join(r.Flags, ", ") + "]"
+	}
+	if r.MaxWeight > 0 {
+		str += fmt.Sprintf(" <= %.2fkg", float64(r.MaxWeight)/1000)
+	}
+	return str
+}
+
+// Matches returns if the rule applies to the item.
+func (r lootRule) Matches(item *Item) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.Pattern != "" {
+		if ok, _ := path.Match(strings.ToLower(r.Pattern), strings.ToLower(item.GetName())); !ok {
+			return false
+		}
+	}
+	if is, ok := itemCategories[r.Category]; ok && !is(item.Type) {
+		return false
+	}
+	for _, filter := range itemFilters {
+		if slices.Contains(r.Flags, filter.name) && !filter.match(item) {
+			return false
+		}
+	}
+	if r.MaxWeight > 0 && item.StackWeight() > r.MaxWeight {
+		return false
+	}
+	return true
+}
+
+// looter applies loot rules to items as they appear, taking at most one action per lootInterval.
+type looter struct {
+	sync.Mutex
+	queue   []messages.Message
+	handled map[int32]bool // Items that have been acted upon, so that an item is never bounced between the ground and the inventory.
+	timer   *time.Timer
+}
+
+// lootRulesKey returns the preferences key for the current character's loot rules.
+func (mgr *Manager) lootRulesKey() string {
+	return "lootRules-" + mgr.playerName
+}
+
+// loadLootRules loads the current character's loot rules from preferences.
+func (mgr *Manager) loadLootRules() {
+	mgr.lootRules = nil
+	for _, entry := range mgr.app.Preferences().StringList(mgr.lootRulesKey()) {
+		var rule lootRule
+		if err := json.Unmarshal([]byte(entry), &rule); err != nil {
+			fmt.Println("error unmarshalling loot rule:", err)
+			continue
+		}
+		mgr.lootRules = append(mgr.lootRules, rule)
+	}
+	mgr.lootEnabled = mgr.app.Preferences().Bool(mgr.lootRulesKey() + "-enabled")
+}
+
+// saveLootRules saves the current character's loot rules to preferences.
+func (mgr *Manager) saveLootRules() {
+	var entries []string
+	for _, rule := range mgr.lootRules {
+		b, err := json.Marshal(rule)
+		if err != nil {
+			fmt.Println("error marshalling loot rule:", err)
+			continue
+		}
+		entries = append(entries, string(b))
+	}
+	mgr.app.Preferences().SetStringList(mgr.lootRulesKey(), entries)
+}
+
+// LootEnabled returns if loot rules are being applied.
+func (mgr *Manager) LootEnabled() bool {
+	return mgr.lootEnabled
+}
+
+// SetLootEnabled sets if loot rules are applied, applying them to the items already present if enabled.
+func (mgr *Manager) SetLootEnabled(enabled bool) {
+	mgr.lootEnabled = enabled
+	mgr.app.Preferences().SetBool(mgr.lootRulesKey()+"-enabled", enabled)
+	if enabled {
+		mgr.applyLootRulesToAll()
+	}
+}
+
+// applyLootRulesToAll applies the loot rules to every item on the ground and in the player's inventory.
+func (mgr *Manager) applyLootRulesToAll() {
+	for _, item := range mgr.store.Children(0) {
+		mgr.applyLootRules(item)
+	}
+	for _, item := range mgr.store.Children(mgr.playerTag) {
+		mgr.applyLootRules(item)
+	}
+}
+
+// applyLootRules queues the action of the first rule that matches the item, if any.
+func (mgr *Manager) applyLootRules(item *Item) {
+	if !mgr.lootEnabled || mgr.playerTag == 0 {
+		return
+	}
+	if item.Location != 0 && item.Location != mgr.playerTag {
+		return
+	}
+	for _, rule := range mgr.lootRules {
+		if !rule.Matches(item) {
+			continue
+		}
+		var msg messages.Message
+		switch {
+		case rule.Action == lootPickup && item.Location == 0 && !item.Flags.NoPick():
+			msg = &messages.MessageMove{To: mgr.playerTag, Tag: item.Tag, Nrof: 0}
+		case rule.Action == lootDrop && item.Location == mgr.playerTag && !item.Flags.Locked() && !item.Flags.Applied():
+			msg = &messages.MessageMove{To: 0, Tag: item.Tag, Nrof: 0}
+		case rule.Action == lootMark && item.Location == mgr.playerTag:
+			msg = &messages.MessageMark{Tag: item.Tag}
+		default:
+			continue
+		}
+		mgr.queueLoot(item.Tag, msg)
+		return
+	}
+}
+
+// queueLoot queues a message to be sent for a loot rule, unless the item has already been acted upon.
+func (mgr *Manager) queueLoot(tag int32, msg messages.Message) {
+	mgr.looter.Lock()
+	defer mgr.looter.Unlock()
+	if mgr.looter.handled == nil {
+		mgr.looter.handled = make(map[int32]bool)
+	}
+	if mgr.looter.handled[tag] {
+		return
+	}
+	mgr.looter.handled[tag] = true
+	mgr.looter.queue = append(mgr.looter.queue, msg)
+	if mgr.looter.timer == nil {
+		mgr.looter.timer = time.AfterFunc(0, mgr.sendLoot)
+	}
+}
+
+// sendLoot sends the next queued loot message, then waits before sending the next.
+func (mgr *Manager) sendLoot() {
+	mgr.looter.Lock()
+	defer mgr.looter.Unlock()
+	if len(mgr.looter.queue) == 0 || !mgr.lootEnabled {
+		mgr.looter.queue = nil
+		mgr.looter.timer = nil
+		return
+	}
+	mgr.conn.Send(mgr.looter.queue[0])
+	mgr.looter.queue = mgr.looter.queue[1:]
+	mgr.looter.timer = time.AfterFunc(lootInterval, mgr.sendLoot)
+}
+
+// ShowLootMenu shows a menu for toggling loot rules and editing them, positioned relative to the given object.
+func (mgr *Manager) ShowLootMenu(pos fyne.Position, obj fyne.CanvasObject) {
+	toggle := fyne.NewMenuItem("auto-loot", func() {
+		mgr.SetLootEnabled(!mgr.lootEnabled)
+	})
+	toggle.Checked = mgr.lootEnabled
+	edit := fyne.NewMenuItem("edit rules", func() {
+		mgr.ShowLootRules()
+	})
+	widget.NewPopUpMenu(fyne.NewMenu("Loot", toggle, edit), mgr.window.Canvas()).ShowAtRelativePosition(pos, obj)
+}
+
+// ShowLootRules shows a dialog for adding, removing, and toggling the current character's loot rules.
+func (mgr *Manager) ShowLootRules() {
+	var list *widget.List
+	list = widget.NewList(
+		func() int {
+			return len(mgr.lootRules)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewCheck("", nil), widget.NewButton("delete", nil), widget.NewLabel(""))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			c := o.(*fyne.Container)
+			c.Objects[0].(*widget.Label).SetText(mgr.lootRules[i].String())
+			check := c.Objects[1].(*widget.Check)
+			check.OnChanged = nil
+			check.SetChecked(mgr.lootRules[i].Enabled)
+			check.OnChanged = func(checked bool) {
+				mgr.lootRules[i].Enabled = checked
+				mgr.saveLootRules()
+			}
+			c.Objects[2].(*widget.Button).OnTapped = func() {
+				mgr.lootRules = slices.Delete(mgr.lootRules, i, i+1)
+				mgr.saveLootRules()
+				list.Refresh()
+			}
+		},
+	)
+
+	add := widget.NewButton("add rule", func() {
+		mgr.showAddLootRule(func(rule lootRule) {
+			mgr.lootRules = append(mgr.lootRules, rule)
+			mgr.saveLootRules()
+			list.Refresh()
+			mgr.applyLootRulesToAll()
+		})
+	})
+
+	enabled := widget.NewCheck("auto-loot", func(checked bool) {
+		mgr.SetLootEnabled(checked)
+	})
+	enabled.SetChecked(mgr.lootEnabled)
+
+	dialog := layouts.NewDialog(mgr.window)
+	dialog.Full = true
+
+	content := container.NewBorder(nil, container.NewHBox(enabled, layout.NewSpacer(), add), nil, nil, list)
+	popup := cfwidgets.NewPopUp(container.New(dialog, content), mgr.window.Canvas())
+	popup.ShowCentered(mgr.window.Canvas())
+}
+
+// showAddLootRule shows a form for creating a new loot rule.
+func (mgr *Manager) showAddLootRule(cb func(rule lootRule)) {
+	action := widget.NewSelect(lootActions, nil)
+	action.SetSelected(lootPickup)
+
+	pattern := widget.NewEntry()
+	pattern.SetPlaceHolder("*arrow*")
+
+	var categories []string
+	for name := range itemCategories {
+		categories = append(categories, name)
+	}
+	slices.Sort(categories)
+	category := widget.NewSelect(append([]string{"any"}, categories...), nil)
+	category.SetSelected("any")
+
+	var filterNames []string
+	for _, filter := range itemFilters {
+		filterNames = append(filterNames, filter.name)
+	}
+	flags := widget.NewCheckGroup(filterNames, nil)
+	flags.Horizontal = true
+
+	maxWeight := widget.NewEntry()
+	maxWeight.SetPlaceHolder("kg, or empty for any")
+
+	dialog.ShowForm("Loot Rule", "Add", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Action", action),
+		widget.NewFormItem("Name", pattern),
+		widget.NewFormItem("Type", category),
+		widget.NewFormItem("Flags", flags),
+		widget.NewFormItem("Max Weight", maxWeight),
+	}, func(b bool) {
+		if !b {
+			return
+		}
+		rule := lootRule{
+			Enabled: true,
+			Pattern: strings.TrimSpace(pattern.Text),
+			Flags:   flags.Selected,
+			Action:  action.Selected,
+		}
+		if category.Selected != "any" {
+			rule.Category = category.Selected
+		}
+		if kg, err := strconv.ParseFloat(strings.TrimSpace(maxWeight.Text), 64); err == nil && kg > 0 {
+			rule.MaxWeight = int32(kg * 1000)
+		}
+		cb(rule)
+	}, mgr.window)
+}
@@ -0,0 +1,223 @@
+package items
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/termfire/messages"
+)
+
+// coinNameRegexp matches the names of coins. Amber coins are named amberium coins.
+var coinNameRegexp = regexp.MustCompile(`^(silver|gold|platinum|jade|amber)(?:ium)? coins?$`)
+
+// coinValue returns the value in silver of the item if it is a stack of coins.
+func coinValue(item *Item) (int64, bool) {
+	match := coinNameRegexp.FindStringSubmatch(strings.ToLower(item.Name))
+	if match == nil {
+		return 0, false
+	}
+	return coinValues[match[1]] * int64(max(1, item.Nrof)), true
+}
+
+// parseNamePrice returns the price in silver found in an item's name, such as "sword (3 gold)", if any.
+func parseNamePrice(name string) (int64, bool) {
+	start := strings.LastIndex(name, "(")
+	if start == -1 {
+		return 0, false
+	}
+	matches := coinRegexp.FindAllStringSubmatch(name[start:], -1)
+	if matches == nil {
+		return 0, false
+	}
+	var value int64
+	for _, match := range matches {
+		count, _ := strconv.ParseInt(match[1], 10, 64)
+		value += count * coinValues[match[2]]
+	}
+	return value, true
+}
+
+// formatPrice formats a price in silver.
+func formatPrice(silver int64) string {
+	return fmt.Sprintf("%d silver", silver)
+}
+
+// archKey returns the key used to cache an item's price. The client is never told an item's archetype, so its face and name stand in for it.
+func archKey(item *Item) string {
+	return fmt.Sprintf("%d:%s", item.Face, item.Name)
+}
+
+// loadPrices loads the cache of prices per item from preferences.
+func (mgr *Manager) loadPrices() {
+	mgr.prices = make(map[string]int64)
+	for _, entry := range mgr.app.Preferences().StringList("priceCache") {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if price, err := strconv.ParseInt(value, 10, 64); err == nil {
+			mgr.prices[key] = price
+		}
+	}
+}
+
+// cachePrice caches the price of a single one of the item, as found when examining it while unpaid.
+func (mgr *Manager) cachePrice(item *Item, value int64) {
+	mgr.pricesLock.Lock()
+	defer mgr.pricesLock.Unlock()
+	price := value / int64(max(1, item.Nrof))
+	key := archKey(item)
+	if mgr.prices[key] == price {
+		return
+	}
+	mgr.prices[key] = price
+	var entries []string
+	for key, price := range mgr.prices {
+		entries = append(entries, fmt.Sprintf("%s=%d", key, price))
+	}
+	mgr.app.Preferences().SetStringList("priceCache", entries)
+}
+
+// price returns the price of the whole stack, whether it is known from examining the item, its name, or the price cache. estimated is true if it came from the cache.
+func (mgr *Manager) price(item *Item) (price int64, estimated bool, ok bool) {
+	if value, ok := mgr.itemValue(item); ok {
+		return value, false, true
+	}
+	if price, ok := parseNamePrice(item.Name); ok {
+		return price, false, true
+	}
+	mgr.pricesLock.Lock()
+	cached, found := mgr.prices[archKey(item)]
+	mgr.pricesLock.Unlock()
+	if found {
+		return cached * int64(max(1, item.Nrof)), true, true
+	}
+	return 0, false, false
+}
+
+// unpaidTotal returns the total price of the unpaid items the player carries, along with how many of them have no known price.
+func (mgr *Manager) unpaidTotal() (total int64, unpriced int, count int) {
+	mgr.walkPlayerItems(func(item *Item) {
+		if !item.Flags.Unpaid() {
+			return
+		}
+		count++
+		if price, _, ok := mgr.price(item); ok {
+			total += price
+		} else {
+			unpriced++
+		}
+	})
+	return total, unpriced, count
+}
+
+// carriedMoney returns the value in silver of the coins the player carries, including those in containers.
+func (mgr *Manager) carriedMoney() int64 {
+	var money int64
+	mgr.walkPlayerItems(func(item *Item) {
+		if value, ok := coinValue(item); ok && !item.Flags.Unpaid() {
+			money += value
+		}
+	})
+	return money
+}
+
+// walkPlayerItems calls the function with each item the player carries, including those in containers.
+func (mgr *Manager) walkPlayerItems(cb func(item *Item)) {
+	var walk func(tag int32)
+	walk = func(tag int32) {
+		for _, item := range mgr.store.Children(tag) {
+			cb(item)
+			walk(item.Tag)
+		}
+	}
+	walk(mgr.playerTag)
+}
+
+// dropUnpaid drops every unpaid item the player carries, returning them to the shop.
+func (mgr *Manager) dropUnpaid() {
+	var unpaid []int32
+	mgr.walkPlayerItems(func(item *Item) {
+		if item.Flags.Unpaid() {
+			unpaid = append(unpaid, item.Tag)
+		}
+	})
+	for _, tag := range unpaid {
+		mgr.conn.Send(&messages.MessageMove{
+			To:   0,
+			Tag:  tag,
+			Nrof: 0, // all
+		})
+	}
+}
+
+// makeShopBar creates the bar showing the total of unpaid items against the player's money, along with paying for or dropping them.
+func (iw *InventoryWidget) makeShopBar() *fyne.Container {
+	iw.shopSummary = widget.NewLabel("")
+	iw.shopSummary.Wrapping = fyne.TextWrapWord
+	pay := widget.NewButton("pay", func() {
+		iw.inv.mgr.showPay()
+	})
+	drop := widget.NewButton("drop unpaid", func() {
+		iw.inv.mgr.dropUnpaid()
+	})
+	iw.shopBar = container.NewBorder(nil, nil, nil, container.NewHBox(pay, drop), iw.shopSummary)
+	return iw.shopBar
+}
+
+// showPay walks the player through paying for their unpaid items. Crossfire has no command to pay with: the server charges for unpaid items when the player steps off the shop floor, refusing to let them leave if they can't afford it. So this checks that the items can be afforded and says how to pay, offering to drop them otherwise.
+func (mgr *Manager) showPay() {
+	total, unpriced, _ := mgr.unpaidTotal()
+	money := mgr.carriedMoney()
+	if total > money {
+		dialog.ShowConfirm("Pay", fmt.Sprintf("You are %s short of the %s owed. Drop every unpaid item?", formatPrice(total-money), formatPrice(total)), func(b bool) {
+			if b {
+				mgr.dropUnpaid()
+			}
+		}, mgr.window)
+		return
+	}
+	text := fmt.Sprintf("Walk out of the shop to pay %s.", formatPrice(total))
+	if unpriced > 0 {
+		text += fmt.Sprintf(" %d items have no known price, so the total may be higher.", unpriced)
+	}
+	dialog.ShowInformation("Pay", text, mgr.window)
+}
+
+// affectsShop returns if a change to the given tag may change the shop bar: it is the player, an unpaid item, or something the player carries, such as money or an item that was just paid for.
+func (mgr *Manager) affectsShop(tag int32) bool {
+	if tag == mgr.playerTag {
+		return true
+	}
+	item := mgr.store.Get(tag)
+	return item != nil && (item.Flags.Unpaid() || item.Location == mgr.playerTag)
+}
+
+// refreshShop updates the shop bar, hiding it if the player carries nothing unpaid.
+func (iw *InventoryWidget) refreshShop() {
+	mgr := iw.inv.mgr
+	total, unpriced, count := mgr.unpaidTotal()
+	if iw.inv.kind() != "player" || count == 0 {
+		iw.shopBar.Hide()
+		return
+	}
+	money := mgr.carriedMoney()
+	text := fmt.Sprintf("Unpaid: %s of %s carried", formatPrice(total), formatPrice(money))
+	if unpriced > 0 {
+		text += fmt.Sprintf(" (%d unpriced, examine to price)", unpriced)
+	}
+	if total > money {
+		text += fmt.Sprintf(", %s short", formatPrice(total-money))
+		iw.shopSummary.Importance = widget.DangerImportance
+	} else {
+		iw.shopSummary.Importance = widget.SuccessImportance
+	}
+	iw.shopSummary.SetText(text)
+	iw.shopBar.Show()
+}
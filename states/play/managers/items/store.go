@@ -0,0 +1,269 @@
+package items
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/kettek/termfire/messages"
+)
+
+// Store holds every item the client knows about, indexed by tag. Each item knows the tag of what contains it, be it the player, a container, or the ground (0), so an inventory is simply the children of a tag.
+//
+// The store is safe to use from any goroutine. Callbacks are called without the lock held, after each change is complete, so they may read the store.
+type Store struct {
+	lock     sync.RWMutex
+	items    map[int32]*Item
+	children map[int32][]*Item
+	names    map[string][]*Item
+	onChange []func(tag int32)
+	onArrive []func(item *Item)
+}
+
+// storeChanges collects the notifications of a change, to be sent once the lock is released.
+type storeChanges struct {
+	tags    []int32
+	arrived []*Item
+}
+
+func (c *storeChanges) changed(tags ...int32) {
+	c.tags = append(c.tags, tags...)
+}
+
+func (c *storeChanges) arrive(item *Item) {
+	c.arrived = append(c.arrived, item)
+}
+
+// NewStore creates a new, empty store.
+func NewStore() *Store {
+	return &Store{
+		items:    make(map[int32]*Item),
+		children: make(map[int32][]*Item),
+		names:    make(map[string][]*Item),
+	}
+}
+
+// OnChange registers a callback that is called with the tag of each location whose children changed, as well as with the tag of any item that was itself updated.
+func (s *Store) OnChange(cb func(tag int32)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onChange = append(s.onChange, cb)
+}
+
+// OnArrive registers a callback that is called with each item that is added or moved into a new location.
+func (s *Store) OnArrive(cb func(item *Item)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onArrive = append(s.onArrive, cb)
+}
+
+// notify sends the collected notifications. It must be called without the lock held.
+func (s *Store) notify(c *storeChanges) {
+	s.lock.RLock()
+	onChange, onArrive := s.onChange, s.onArrive
+	s.lock.RUnlock()
+	for _, tag := range c.tags {
+		for _, cb := range onChange {
+			cb(tag)
+		}
+	}
+	for _, item := range c.arrived {
+		for _, cb := range onArrive {
+			cb(item)
+		}
+	}
+}
+
+// Get returns the item with the given tag, or nil if it is not known.
+func (s *Store) Get(tag int32) *Item {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.items[tag]
+}
+
+// Children returns a copy of the items in the given location, sorted by type.
+func (s *Store) Children(location int32) []*Item {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return slices.Clone(s.children[location])
+}
+
+// ByName returns an item with the given name, or nil if there is none.
+func (s *Store) ByName(name string) *Item {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if items := s.names[name]; len(items) > 0 {
+		return items[0]
+	}
+	return nil
+}
+
+// Find returns every item that matches the given function. The function is called with the store locked for reading, so it must not change the store.
+func (s *Store) Find(match func(item *Item) bool) []*Item {
+	s.lock.RLock()
+	var found []*Item
+	for _, item := range s.items {
+		if match(item) {
+			found = append(found, item)
+		}
+	}
+	s.lock.RUnlock()
+	slices.SortFunc(found, func(a, b *Item) int {
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+	return found
+}
+
+// Add adds an item to the given location, as per an item2 message. If the item is already known, it is replaced and moved if needed.
+func (s *Store) Add(location int32, obj messages.ItemObject) *Item {
+	var c storeChanges
+	s.lock.Lock()
+	item := s.add(location, obj, &c)
+	s.lock.Unlock()
+	s.notify(&c)
+	return item
+}
+
+func (s *Store) add(location int32, obj messages.ItemObject, c *storeChanges) *Item {
+	item, ok := s.items[obj.Tag]
+	if !ok {
+		item = &Item{ItemObject: obj, Location: location}
+		s.items[obj.Tag] = item
+		s.index(item)
+		s.attach(item)
+		c.changed(location)
+		c.arrive(item)
+		return item
+	}
+
+	s.unindex(item)
+	item.ItemObject = obj
+	s.index(item)
+	if item.Location != location {
+		s.move(item, location, c)
+		return item
+	}
+	s.sort(location)
+	c.changed(location, obj.Tag)
+	return item
+}
+
+// Move moves an item into another location. It returns false if the item is not known.
+func (s *Store) Move(tag, location int32) bool {
+	var c storeChanges
+	s.lock.Lock()
+	item, ok := s.items[tag]
+	if ok {
+		s.move(item, location, &c)
+	}
+	s.lock.Unlock()
+	s.notify(&c)
+	return ok
+}
+
+func (s *Store) move(item *Item, location int32, c *storeChanges) {
+	from := item.Location
+	if from == location {
+		return
+	}
+	s.detach(item)
+	item.Location = location
+	s.attach(item)
+	c.changed(from, location)
+	c.arrive(item)
+}
+
+// Update applies an update item message, moving the item if its location changed. It returns false if the item is not known.
+func (s *Store) Update(msg *messages.MessageUpdateItem) bool {
+	var c storeChanges
+	s.lock.Lock()
+	item, ok := s.items[msg.Tag]
+	if ok {
+		s.unindex(item)
+		item.Update(msg)
+		s.index(item)
+		for _, f := range msg.Fields {
+			if location, ok := f.(messages.MessageUpdateItemLocation); ok {
+				s.move(item, int32(location), &c)
+			}
+		}
+		s.sort(item.Location)
+		c.changed(item.Location, item.Tag)
+	}
+	s.lock.Unlock()
+	s.notify(&c)
+	return ok
+}
+
+// Delete removes an item, along with anything it contains.
+func (s *Store) Delete(tag int32) {
+	var c storeChanges
+	s.lock.Lock()
+	if item, ok := s.items[tag]; ok {
+		s.clear(tag, &c)
+		s.detach(item)
+		s.unindex(item)
+		delete(s.items, tag)
+		c.changed(item.Location)
+	}
+	s.lock.Unlock()
+	s.notify(&c)
+}
+
+// Clear removes everything in the given location, along with anything they contain.
+func (s *Store) Clear(location int32) {
+	var c storeChanges
+	s.lock.Lock()
+	s.clear(location, &c)
+	s.lock.Unlock()
+	s.notify(&c)
+}
+
+func (s *Store) clear(location int32, c *storeChanges) {
+	children := s.children[location]
+	if len(children) == 0 {
+		return
+	}
+	for _, child := range children {
+		s.clear(child.Tag, c)
+		s.unindex(child)
+		delete(s.items, child.Tag)
+	}
+	delete(s.children, location)
+	c.changed(location)
+}
+
+// attach adds the item to its location's children.
+func (s *Store) attach(item *Item) {
+	s.children[item.Location] = append(s.children[item.Location], item)
+	s.sort(item.Location)
+}
+
+// detach removes the item from its location's children.
+func (s *Store) detach(item *Item) {
+	s.children[item.Location] = slices.DeleteFunc(s.children[item.Location], func(other *Item) bool {
+		return other == item
+	})
+	if len(s.children[item.Location]) == 0 {
+		delete(s.children, item.Location)
+	}
+}
+
+func (s *Store) sort(location int32) {
+	slices.SortStableFunc(s.children[location], func(a, b *Item) int {
+		return int(a.Type) - int(b.Type)
+	})
+}
+
+func (s *Store) index(item *Item) {
+	s.names[item.Name] = append(s.names[item.Name], item)
+}
+
+func (s *Store) unindex(item *Item) {
+	s.names[item.Name] = slices.DeleteFunc(s.names[item.Name], func(other *Item) bool {
+		return other == item
+	})
+	if len(s.names[item.Name]) == 0 {
+		delete(s.names, item.Name)
+	}
+}
@@ -0,0 +1,148 @@
+package items
+
+import (
+	"testing"
+
+	"github.com/kettek/termfire/messages"
+)
+
+func hasChild(s *Store, location, tag int32) bool {
+	for _, item := range s.Children(location) {
+		if item.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStoreMove(t *testing.T) {
+	s := NewStore()
+	s.Add(1, messages.ItemObject{Tag: 10, Name: "sword"})
+
+	if !s.Move(10, 2) {
+		t.Fatal("expected move of known item to succeed")
+	}
+	if hasChild(s, 1, 10) {
+		t.Error("item still in old location")
+	}
+	if _, ok := s.children[1]; ok {
+		t.Error("empty old location was not removed")
+	}
+	if !hasChild(s, 2, 10) {
+		t.Error("item missing from new location")
+	}
+	if item := s.Get(10); item.Location != 2 {
+		t.Errorf("location = %d, want 2", item.Location)
+	}
+	if s.Move(99, 2) {
+		t.Error("expected move of unknown item to fail")
+	}
+}
+
+func TestStoreDeleteContainer(t *testing.T) {
+	s := NewStore()
+	s.Add(1, messages.ItemObject{Tag: 10, Name: "sack"})
+	s.Add(10, messages.ItemObject{Tag: 11, Name: "apple"})
+	s.Add(10, messages.ItemObject{Tag: 12, Name: "pouch"})
+	s.Add(12, messages.ItemObject{Tag: 13, Name: "coin"})
+
+	s.Delete(10)
+
+	for _, tag := range []int32{10, 11, 12, 13} {
+		if s.Get(tag) != nil {
+			t.Errorf("item %d still known", tag)
+		}
+	}
+	for _, name := range []string{"sack", "apple", "pouch", "coin"} {
+		if s.ByName(name) != nil {
+			t.Errorf("name %q still indexed", name)
+		}
+	}
+	if len(s.Children(1)) != 0 || len(s.Children(10)) != 0 || len(s.Children(12)) != 0 {
+		t.Error("children left behind")
+	}
+}
+
+func TestStoreClearNested(t *testing.T) {
+	s := NewStore()
+	s.Add(1, messages.ItemObject{Tag: 10, Name: "chest"})
+	s.Add(10, messages.ItemObject{Tag: 11, Name: "bag"})
+	s.Add(11, messages.ItemObject{Tag: 12, Name: "ring"})
+	s.Add(2, messages.ItemObject{Tag: 20, Name: "rock"})
+
+	s.Clear(1)
+
+	for _, tag := range []int32{10, 11, 12} {
+		if s.Get(tag) != nil {
+			t.Errorf("item %d still known", tag)
+		}
+	}
+	if s.ByName("ring") != nil {
+		t.Error("nested name still indexed")
+	}
+	if s.Get(20) == nil || !hasChild(s, 2, 20) {
+		t.Error("item in another location was cleared")
+	}
+}
+
+func TestStoreUpdateLocation(t *testing.T) {
+	s := NewStore()
+	s.Add(1, messages.ItemObject{Tag: 10, Name: "helmet"})
+	var changed []int32
+	s.OnChange(func(tag int32) {
+		changed = append(changed, tag)
+	})
+
+	ok := s.Update(&messages.MessageUpdateItem{
+		Tag:    10,
+		Fields: []messages.MessageUpdateItemField{messages.MessageUpdateItemLocation(5)},
+	})
+	if !ok {
+		t.Fatal("expected update of known item to succeed")
+	}
+	if hasChild(s, 1, 10) || !hasChild(s, 5, 10) {
+		t.Error("item was not re-parented")
+	}
+	if s.Get(10).Location != 5 {
+		t.Errorf("location = %d, want 5", s.Get(10).Location)
+	}
+	var sawOld, sawNew bool
+	for _, tag := range changed {
+		sawOld = sawOld || tag == 1
+		sawNew = sawNew || tag == 5
+	}
+	if !sawOld || !sawNew {
+		t.Errorf("changes %v should include both locations", changed)
+	}
+}
+
+func TestStoreChildrenCopy(t *testing.T) {
+	s := NewStore()
+	s.Add(1, messages.ItemObject{Tag: 10, Name: "sword", Type: 2})
+	s.Add(1, messages.ItemObject{Tag: 11, Name: "shield", Type: 1})
+
+	children := s.Children(1)
+	s.Move(11, 2)
+	s.Add(1, messages.ItemObject{Tag: 12, Name: "arrow", Type: 0})
+
+	if len(children) != 2 || children[0].Tag != 11 || children[1].Tag != 10 {
+		t.Errorf("earlier children changed to %v", children)
+	}
+}
+
+func TestStoreCallbacksMayRead(t *testing.T) {
+	s := NewStore()
+	var seen int
+	s.OnChange(func(tag int32) {
+		seen += len(s.Children(tag))
+	})
+	s.OnArrive(func(item *Item) {
+		if s.Get(item.Tag) == nil {
+			t.Errorf("arrived item %d not in store", item.Tag)
+		}
+	})
+	s.Add(1, messages.ItemObject{Tag: 10, Name: "sword"})
+	if seen != 1 {
+		t.Errorf("seen = %d children, want 1", seen)
+	}
+}
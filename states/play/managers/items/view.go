@@ -0,0 +1,243 @@
+package items
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/data"
+)
+
+// Keys that inventory items can be sorted by.
+const (
+	sortType   = "type"
+	sortName   = "name"
+	sortWeight = "weight"
+	sortValue  = "value"
+)
+
+var sortKeys = []string{sortType, sortName, sortWeight, sortValue}
+
+// itemFilter is a flag that items can be filtered by, shown using the flag's icon.
+type itemFilter struct {
+	name  string
+	icon  string
+	match func(item *Item) bool
+}
+
+var itemFilters = []itemFilter{
+	{"applied", "icon_applied.png", func(item *Item) bool { return item.Flags.Applied() }},
+	{"unidentified", "icon_unidentified.png", func(item *Item) bool { return item.Flags.Unidentified() }},
+	{"cursed", "icon_cursed.png", func(item *Item) bool { return item.Flags.Cursed() || item.Flags.Damned() }},
+	{"magic", "icon_magic.png", func(item *Item) bool { return item.Flags.Magic() }},
+	{"unpaid", "icon_unpaid.png", func(item *Item) bool { return item.Flags.Unpaid() }},
+	{"locked", "icon_locked.png", func(item *Item) bool { return item.Flags.Locked() }},
+}
+
+// coinValues are the values of each coin relative to silver, used to compare the prices found when examining items.
+var coinValues = map[string]int64{
+	"silver":   1,
+	"gold":     10,
+	"platinum": 50,
+	"jade":     5000,
+	"amber":    500000,
+}
+
+var coinRegexp = regexp.MustCompile(`(\d+) (silver|gold|platinum|jade|amber)`)
+
+// parseValue returns the value in silver of the coins named in an examine line, if any. Only lines that state a price are considered.
+func parseValue(info string) (int64, bool) {
+	if !strings.Contains(info, "cost") && !strings.Contains(info, "worth") && !strings.Contains(info, "offered") {
+		return 0, false
+	}
+	matches := coinRegexp.FindAllStringSubmatch(info, -1)
+	if matches == nil {
+		return 0, false
+	}
+	var value int64
+	for _, match := range matches {
+		count, _ := strconv.ParseInt(match[1], 10, 64)
+		value += count * coinValues[match[2]]
+	}
+	return value, true
+}
+
+// viewOptions controls which of an inventory's items are shown and in what order.
+type viewOptions struct {
+	Search     string   // Only show items whose name contains this.
+	Filters    []string // Only show items with all of these flags.
+	Sort       []string // Keys to sort by, in order of priority.
+	Descending bool
+}
+
+// loadViewOptions loads the view options for the given kind of inventory from preferences.
+func loadViewOptions(prefs fyne.Preferences, kind string) viewOptions {
+	return viewOptions{
+		Search:     prefs.String(kind + "InventorySearch"),
+		Filters:    prefs.StringList(kind + "InventoryFilters"),
+		Sort:       prefs.StringListWithFallback(kind+"InventorySort", []string{sortType}),
+		Descending: prefs.Bool(kind + "InventoryDescending"),
+	}
+}
+
+// save saves the view options for the given kind of inventory to preferences.
+func (v viewOptions) save(prefs fyne.Preferences, kind string) {
+	prefs.SetString(kind+"InventorySearch", v.Search)
+	prefs.SetStringList(kind+"InventoryFilters", v.Filters)
+	prefs.SetStringList(kind+"InventorySort", v.Sort)
+	prefs.SetBool(kind+"InventoryDescending", v.Descending)
+}
+
+// filtered returns if the given filter is enabled.
+func (v viewOptions) filtered(name string) bool {
+	return slices.Contains(v.Filters, name)
+}
+
+// apply returns the items that pass the search and filters, sorted by the sort keys. Values are only known for examined items, so they are looked up with the given function.
+func (v viewOptions) apply(items []*Item, valueOf func(item *Item) (int64, bool)) []*Item {
+	search := strings.ToLower(v.Search)
+	var shown []*Item
+	for _, item := range items {
+		if search != "" && !strings.Contains(strings.ToLower(item.GetName()), search) {
+			continue
+		}
+		matches := true
+		for _, filter := range itemFilters {
+			if v.filtered(filter.name) && !filter.match(item) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			shown = append(shown, item)
+		}
+	}
+
+	slices.SortStableFunc(shown, func(a, b *Item) int {
+		for _, key := range v.Sort {
+			if key == sortValue {
+				aValue, aKnown := valueOf(a)
+				bValue, bKnown := valueOf(b)
+				if aKnown != bKnown {
+					// Items of unknown value come last either way.
+					if aKnown {
+						return -1
+					}
+					return 1
+				}
+				if c := cmp.Compare(aValue, bValue); c != 0 {
+					if v.Descending {
+						return -c
+					}
+					return c
+				}
+				continue
+			}
+			if c := compareBy(key, a, b); c != 0 {
+				if v.Descending {
+					return -c
+				}
+				return c
+			}
+		}
+		return 0
+	})
+	return shown
+}
+
+// compareBy compares two items by the given sort key, other than value.
+func compareBy(key string, a, b *Item) int {
+	switch key {
+	case sortType:
+		return cmp.Compare(a.Type, b.Type)
+	case sortName:
+		return strings.Compare(strings.ToLower(a.GetName()), strings.ToLower(b.GetName()))
+	case sortWeight:
+		return cmp.Compare(a.StackWeight(), b.StackWeight())
+	}
+	return 0
+}
+
+// makeViewBar creates the search field, flag filter toggles, and sort controls for the inventory.
+func (iw *InventoryWidget) makeViewBar() fyne.CanvasObject {
+	inv := iw.inv
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("search")
+	search.SetText(inv.view.Search)
+	search.OnChanged = func(text string) {
+		view := inv.view
+		view.Search = text
+		inv.setView(view)
+	}
+
+	filters := container.NewHBox()
+	for _, filter := range itemFilters {
+		var button *widget.Button
+		button = widget.NewButtonWithIcon("", data.GetResource(filter.icon), func() {
+			view := inv.view
+			if view.filtered(filter.name) {
+				view.Filters = slices.DeleteFunc(slices.Clone(view.Filters), func(name string) bool {
+					return name == filter.name
+				})
+				button.Importance = widget.MediumImportance
+			} else {
+				view.Filters = append(slices.Clone(view.Filters), filter.name)
+				button.Importance = widget.HighImportance
+			}
+			button.Refresh()
+			inv.setView(view)
+		})
+		if inv.view.filtered(filter.name) {
+			button.Importance = widget.HighImportance
+		}
+		filters.Add(button)
+	}
+
+	// The first sort key is required, while the second is optional.
+	var primary, secondary *widget.Select
+	setSort := func(string) {
+		if primary == nil || secondary == nil {
+			return
+		}
+		view := inv.view
+		view.Sort = []string{primary.Selected}
+		if secondary.Selected != "none" && secondary.Selected != primary.Selected {
+			view.Sort = append(view.Sort, secondary.Selected)
+		}
+		inv.setView(view)
+	}
+	primary = widget.NewSelect(sortKeys, setSort)
+	secondary = widget.NewSelect(append([]string{"none"}, sortKeys...), setSort)
+	if len(inv.view.Sort) > 0 {
+		primary.Selected = inv.view.Sort[0]
+	}
+	secondary.Selected = "none"
+	if len(inv.view.Sort) > 1 {
+		secondary.Selected = inv.view.Sort[1]
+	}
+
+	orderIcon := func(descending bool) fyne.Resource {
+		if descending {
+			return data.GetResource("icon_descending.png")
+		}
+		return data.GetResource("icon_ascending.png")
+	}
+	var order *widget.Button
+	order = widget.NewButtonWithIcon("", orderIcon(inv.view.Descending), func() {
+		view := inv.view
+		view.Descending = !view.Descending
+		order.SetIcon(orderIcon(view.Descending))
+		inv.setView(view)
+	})
+
+	return container.NewVBox(
+		search,
+		container.NewBorder(nil, nil, filters, container.NewHBox(primary, secondary, order)),
+	)
+}
@@ -32,6 +32,22 @@ type InventoryWidget struct {
 	itemList             *widget.List
 	itemListScroll       *container.Scroll
 	itemInfo             *widget.RichText
+	itemStats            *fyne.Container
+	compare              bool  // Whether to compare the selected item against the applied item it would replace.
+	compareTag           int32 // The tag of the item being compared against, if any.
+	summary              *widget.Label
+	shopSummary          *widget.Label
+	shopBar              *fyne.Container
+	containers           *fyne.Container
+	containersScroll     *container.Scroll
+	containerTargets     []dropTarget
+	containersStale      bool // Whether the container sections changed during a drag.
+	dropZones            *fyne.Container
+	dropZoneTargets      []dropTarget
+	dragLayer            *fyne.Container
+	dragIcon             *canvas.Image
+	dragging             *Item
+	dragPosition         fyne.Position
 	toolbar              *widget.Toolbar
 	toolbarActions       [5]*widget.ToolbarAction
 	fullContentContainer *fyne.Container
@@ -40,6 +56,7 @@ type InventoryWidget struct {
 	minimal              bool
 
 	selectedIndex int
+	reselecting   bool
 }
 
 func newInventoryWidget(inv *Inventory, window fyne.Window, conn *net.Connection) *InventoryWidget {
@@ -55,21 +72,38 @@ func newInventoryWidget(inv *Inventory, window fyne.Window, conn *net.Connection
 			return len(inv.Items)
 		},
 		func() fyne.CanvasObject {
-			return iw.makeEntryTemplate()
+			return newDragEntry(iw, iw.makeEntryTemplate(), func(ev *fyne.DragEvent) {
+				iw.itemList.ScrollToOffset(iw.itemList.GetScrollOffset() - ev.Dragged.DY)
+			}, nil)
 		},
 		func(i widget.ListItemID, o fyne.CanvasObject) {
 			item := inv.Items[i]
-			iw.updateEntry(o.(*fyne.Container), item)
+			entry := o.(*dragEntry)
+			entry.item = item
+			iw.updateEntry(entry.content.(*fyne.Container), item)
 		},
 	)
 	iw.itemList.OnSelected = func(id widget.ListItemID) {
 		iw.selectedIndex = id
+		if iw.reselecting {
+			return
+		}
 		iw.refreshSelected()
 	}
 
+	// Create our weight and value summary, along with the unpaid total for when shopping.
+	iw.summary = widget.NewLabel("")
+	iw.refreshSummary()
+	iw.makeShopBar()
+	iw.refreshShop()
+
 	// Create our item info.
 	iw.itemInfo = widget.NewRichText()
 	iw.itemInfo.Wrapping = fyne.TextWrapWord
+	iw.itemStats = container.NewVBox()
+	iw.compare = inv.mgr.app.Preferences().Bool("inventoryCompare")
+	compareCheck := widget.NewCheck("compare with applied", iw.SetCompare)
+	compareCheck.Checked = iw.compare
 
 	// Create our toolbar.
 	iw.toolbarActions[actionApply] = widget.NewToolbarAction(data.GetResource("icon_apply.png"), func() {
@@ -86,26 +120,13 @@ func newInventoryWidget(inv *Inventory, window fyne.Window, conn *net.Connection
 	})
 	iw.toolbarActions[actionDropSome] = widget.NewToolbarAction(data.GetResource("icon_dropsome.png"), func() {
 		item := iw.inv.Items[iw.selectedIndex]
-
-		countEntry := widget.NewEntry()
-		if item.Nrof > 1 {
-			countEntry.SetText(fmt.Sprintf("%d", item.Nrof/2))
-		} else {
-			countEntry.SetText(fmt.Sprintf("%d", item.Nrof))
-		}
-		dialog.ShowForm("Drop Item", "Drop", "Cancel", []*widget.FormItem{
-			widget.NewFormItem("Amount", countEntry),
-		}, func(b bool) {
-			if !b {
-				return
-			}
-			count, _ := strconv.Atoi(countEntry.Text)
+		iw.promptCount("Drop Item", "Drop", item, func(count int32) {
 			conn.Send(&messages.MessageMove{
 				To:   0, // The ground, for now.
 				Tag:  item.Tag,
-				Nrof: int32(count), // Perhaps add a drop amount prompt?
+				Nrof: count,
 			})
-		}, window)
+		})
 	})
 	iw.toolbarActions[actionLock] = widget.NewToolbarAction(data.GetResource("icon_locked.png"), func() {
 		item := iw.inv.Items[iw.selectedIndex]
@@ -127,14 +148,52 @@ func newInventoryWidget(inv *Inventory, window fyne.Window, conn *net.Connection
 	iw.toolbar = widget.NewToolbar(actions...)
 
 	// Kinda messy setup...
-	iw.itemListScroll = container.NewVScroll(iw.itemInfo)
+	iw.itemListScroll = container.NewVScroll(container.NewVBox(compareCheck, iw.itemStats, iw.itemInfo))
 	listInfoContainer := container.New(&layouts.Inventory{}, iw.itemList, iw.itemListScroll)
 
-	iw.fullContentContainer = container.NewBorder(widget.NewLabel(inv.Item.Name), iw.toolbar, nil, nil, listInfoContainer)
+	// Open containers are shown as sections beneath the list.
+	iw.containers = container.NewVBox()
+	iw.containersScroll = container.NewVScroll(iw.containers)
+	iw.containersScroll.Hide()
+	contentContainer := container.NewBorder(nil, iw.containersScroll, nil, nil, listInfoContainer)
+
+	// Zones for the ground and the player's inventory are shown while dragging an item, as they may not be otherwise visible.
+	iw.dropZones = container.NewGridWithRows(1, iw.makeDropZone("Ground", 0), iw.makeDropZone("Inventory", inv.mgr.playerTag))
+	iw.dropZones.Hide()
+	iw.dragIcon = canvas.NewImageFromResource(data.GetResource("blank.png"))
+	iw.dragIcon.FillMode = canvas.ImageFillContain
+	iw.dragIcon.ScaleMode = canvas.ImageScalePixels
+	iw.dragIcon.Hide()
+	iw.dragLayer = container.NewWithoutLayout(iw.dragIcon)
+
+	iw.fullContentContainer = container.NewStack(
+		container.NewBorder(container.NewVBox(container.NewBorder(nil, nil, widget.NewLabel(inv.Item.Name), iw.summary), iw.shopBar, iw.makeViewBar()), container.NewVBox(iw.dropZones, iw.toolbar), nil, nil, contentContainer),
+		iw.dragLayer,
+	)
 	iw.minContentContainer = container.NewBorder(nil, nil, nil, nil, iw.itemList)
 
 	return iw
 }
+
+// promptCount asks how many of the item to act upon, defaulting to half of the stack.
+func (iw *InventoryWidget) promptCount(title, confirm string, item *Item, cb func(count int32)) {
+	countEntry := widget.NewEntry()
+	if item.Nrof > 1 {
+		countEntry.SetText(fmt.Sprintf("%d", item.Nrof/2))
+	} else {
+		countEntry.SetText(fmt.Sprintf("%d", item.Nrof))
+	}
+	dialog.ShowForm(title, confirm, "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Amount", countEntry),
+	}, func(b bool) {
+		if !b {
+			return
+		}
+		count, _ := strconv.Atoi(countEntry.Text)
+		cb(int32(count))
+	}, iw.window)
+}
+
 func (iw *InventoryWidget) makeEntryTemplate() *fyne.Container {
 	img := canvas.NewImageFromResource(data.GetResource("blank.png"))
 	img.FillMode = canvas.ImageFillContain
@@ -219,10 +278,21 @@ func (iw *InventoryWidget) updateEntry(entry *fyne.Container, item *Item) {
 		img.ScaleMode = canvas.ImageScalePixels
 		flagsContainer.Objects = append(flagsContainer.Objects, img)
 	}
-	if item.TotalWeight > 0 {
-		kg := float64(item.Weight) / 1000
-		weightLabel.SetText(fmt.Sprintf("%.2fkg", kg))
+	weight := ""
+	if item.StackWeight() > 0 {
+		weight = formatWeight(item)
+	}
+	// Show prices of unpaid items, estimating from previously seen prices if not yet examined.
+	if item.Flags.Unpaid() {
+		if price, estimated, ok := iw.inv.mgr.price(item); ok {
+			if estimated {
+				weight += " ~" + formatPrice(price)
+			} else {
+				weight += " " + formatPrice(price)
+			}
+		}
 	}
+	weightLabel.SetText(weight)
 
 	// SetText after because we adjust styling with the flags checks.
 	label.SetText(item.GetName())
@@ -267,11 +337,40 @@ func (iw *InventoryWidget) selectedTag() int32 {
 	return iw.inv.Items[iw.selectedIndex].Tag
 }
 
+// keepSelection moves the selection to follow the selected item to its new index, without examining it again.
+func (iw *InventoryWidget) keepSelection(index int) {
+	if index == iw.selectedIndex {
+		return
+	}
+	iw.reselecting = true
+	iw.itemList.Select(index)
+	iw.reselecting = false
+}
+
 func (iw *InventoryWidget) SetExamineInfo(info string) {
 	iw.itemInfo.Segments = data.TextToRichTextSegments(info)
 	iw.itemInfo.Refresh()
 }
 
+// showExamination shows the examine text of an item along with a table of its parsed stats. A nil examination clears them.
+func (iw *InventoryWidget) showExamination(ex *Examination) {
+	iw.itemStats.RemoveAll()
+	if ex == nil {
+		iw.SetExamineInfo("")
+		return
+	}
+	grid := container.NewGridWithColumns(2)
+	for _, stat := range ex.Stats {
+		name := widget.NewLabel(stat.Name)
+		name.TextStyle.Bold = true
+		grid.Add(name)
+		grid.Add(widget.NewLabel(strconv.FormatFloat(stat.Value, 'f', -1, 64)))
+	}
+	iw.itemStats.Add(grid)
+	iw.itemStats.Refresh()
+	iw.SetExamineInfo(ex.Text)
+}
+
 func (iw *InventoryWidget) refreshSelected() {
 	if iw.selectedIndex < 0 || iw.selectedIndex >= len(iw.inv.Items) {
 		return
@@ -315,20 +414,7 @@ func (iw *InventoryWidget) refreshSelected() {
 		iw.toolbarActions[actionApply].SetIcon(icon)
 	}
 
-	// Set our pending tag so we can re-acquire examineInfo
-	iw.inv.pendingExamineTag = item.Tag
-
-	// Set to our existing examine
-	iw.itemInfo.Segments = data.TextToRichTextSegments(item.examineInfo)
-	iw.itemInfo.Refresh()
+	// Show the cached examination if there is one, otherwise request it. The examination is shown once complete.
+	iw.refreshExamination()
 	iw.itemListScroll.ScrollToTop() // And scroll back up
-
-	// Clear out old. Maybe we should keep this and only reset (and send request) if invalidated by an update or a failed examine (if we can even get that info -- maybe check for a fail string, if one exists)
-	item.examineInfo = ""
-
-	// Send request for item.
-	iw.inv.conn.Send(&messages.MessageExamine{
-		Tag: item.Tag,
-	})
-
 }
@@ -0,0 +1,94 @@
+package keys
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+// Key is a bindable key. Fyne reports the numpad's digits with the same names as the number row, so a key may also be pinned to a physical scan code to tell them apart.
+type Key struct {
+	Name     fyne.KeyName
+	ScanCode int // If non-zero, only the physical key with this scan code matches.
+}
+
+// KeyFromEvent returns the key for the given event. The scan code is kept only for digits, as they are the only keys that are ambiguous.
+func KeyFromEvent(event *fyne.KeyEvent) Key {
+	key := Key{Name: event.Name}
+	if len(event.Name) == 1 && event.Name[0] >= '0' && event.Name[0] <= '9' {
+		key.ScanCode = event.Physical.ScanCode
+	}
+	return key
+}
+
+// Matches returns if the given event is for this key.
+func (k Key) Matches(event *fyne.KeyEvent) bool {
+	if k.Name != event.Name {
+		return false
+	}
+	return k.ScanCode == 0 || k.ScanCode == event.Physical.ScanCode
+}
+
+// String returns the key in the form stored in preferences, which is its name optionally followed by a # and its scan code.
+func (k Key) String() string {
+	if k.ScanCode != 0 {
+		return fmt.Sprintf("%s#%d", k.Name, k.ScanCode)
+	}
+	return string(k.Name)
+}
+
+// Label returns a human-readable name for the key.
+func (k Key) Label() string {
+	if k.ScanCode == 0 {
+		return string(k.Name)
+	}
+	if keypad[k.Name] == k.ScanCode {
+		return "numpad " + string(k.Name)
+	}
+	return fmt.Sprintf("%s (key %d)", k.Name, k.ScanCode)
+}
+
+// ParseKey parses a key from its String form.
+func ParseKey(str string) (Key, error) {
+	if str == "" {
+		return Key{}, fmt.Errorf("empty key")
+	}
+	// Don't mistake a lone # for a scan code separator.
+	if i := strings.LastIndex(str, "#"); i > 0 {
+		code, err := strconv.Atoi(str[i+1:])
+		if err != nil {
+			return Key{}, fmt.Errorf("invalid scan code in key %q: %w", str, err)
+		}
+		return Key{Name: fyne.KeyName(str[:i]), ScanCode: code}, nil
+	}
+	return Key{Name: fyne.KeyName(str)}, nil
+}
+
+// keypad holds the scan codes of the numpad's digits on the current platform.
+var keypad = keypadScanCodes()
+
+// keypadScanCodes returns the scan codes of the numpad's digits for the current platform, as reported by GLFW.
+func keypadScanCodes() map[fyne.KeyName]int {
+	var codes [9]int
+	switch runtime.GOOS {
+	case "windows":
+		codes = [9]int{0x4F, 0x50, 0x51, 0x4B, 0x4C, 0x4D, 0x47, 0x48, 0x49}
+	case "darwin":
+		codes = [9]int{0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59, 0x5B, 0x5C}
+	default: // X11 and Wayland keycodes.
+		codes = [9]int{87, 88, 89, 83, 84, 85, 79, 80, 81}
+	}
+	m := make(map[fyne.KeyName]int)
+	for i, code := range codes {
+		m[fyne.KeyName(strconv.Itoa(i+1))] = code
+	}
+	return m
+}
+
+// numpad returns the key for the given numpad digit.
+func numpad(digit fyne.KeyName) Key {
+	return Key{Name: digit, ScanCode: keypad[digit]}
+}
@@ -0,0 +1,344 @@
+package keys
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/net"
+	"github.com/kettek/mobifire/states/play/cfwidgets"
+	"github.com/kettek/mobifire/states/play/layouts"
+	"github.com/kettek/mobifire/states/play/managers"
+	"github.com/kettek/mobifire/states/play/managers/action"
+	"github.com/kettek/mobifire/states/play/managers/board"
+)
+
+// Bindable actions. Movement actions are named after their movement command.
+const (
+	ActionApply  = "apply"
+	ActionPickup = "pickup"
+)
+
+// movements are the movement actions, in the order shown when remapping.
+var movements = []string{"north", "northeast", "east", "southeast", "south", "southwest", "west", "northwest"}
+
+// slotCount is the number of action slots that can be bound.
+const slotCount = 8
+
+// slotAction returns the action name for the given action slot.
+func slotAction(slot int) string {
+	return fmt.Sprintf("action %d", slot+1)
+}
+
+// Actions returns all bindable actions, in the order shown when remapping.
+func Actions() []string {
+	actions := slices.Clone(movements)
+	actions = append(actions, ActionApply, ActionPickup)
+	for i := range slotCount {
+		actions = append(actions, slotAction(i))
+	}
+	return actions
+}
+
+// DefaultBindings returns the default key bindings. Movement is bound to vi-keys, the arrow keys, and the numpad.
+func DefaultBindings() map[string][]Key {
+	return map[string][]Key{
+		"north":      {{Name: fyne.KeyK}, {Name: fyne.KeyUp}, numpad(fyne.Key8)},
+		"northeast":  {{Name: fyne.KeyU}, numpad(fyne.Key9)},
+		"east":       {{Name: fyne.KeyL}, {Name: fyne.KeyRight}, numpad(fyne.Key6)},
+		"southeast":  {{Name: fyne.KeyN}, numpad(fyne.Key3)},
+		"south":      {{Name: fyne.KeyJ}, {Name: fyne.KeyDown}, numpad(fyne.Key2)},
+		"southwest":  {{Name: fyne.KeyB}, numpad(fyne.Key1)},
+		"west":       {{Name: fyne.KeyH}, {Name: fyne.KeyLeft}, numpad(fyne.Key4)},
+		"northwest":  {{Name: fyne.KeyY}, numpad(fyne.Key7)},
+		ActionApply:  {{Name: fyne.KeyA}, numpad(fyne.Key5)},
+		ActionPickup: {{Name: fyne.KeyComma}},
+		"action 1":   {{Name: fyne.Key1}},
+		"action 2":   {{Name: fyne.Key2}},
+		"action 3":   {{Name: fyne.Key3}},
+		"action 4":   {{Name: fyne.Key4}},
+		"action 5":   {{Name: fyne.Key5}},
+		"action 6":   {{Name: fyne.Key6}},
+		"action 7":   {{Name: fyne.Key7}},
+		"action 8":   {{Name: fyne.Key8}},
+	}
+}
+
+// Manager provides keyboard control of the game on desktop. Movement keys walk, or run while shift is held and fire while ctrl is held.
+type Manager struct {
+	app           fyne.App
+	window        fyne.Window
+	conn          *net.Connection
+	actionManager *action.Manager
+	boardManager  *board.Manager
+	bindings      map[string][]Key
+	held          map[Key]string // Keys that started running or firing, and the command to stop it on release.
+	capture       func(key Key)  // If set, the next key pressed is passed here instead of being handled.
+}
+
+// NewManager creates a new keys manager.
+func NewManager() *Manager {
+	return &Manager{
+		held: make(map[Key]string),
+	}
+}
+
+// SetApp sets the app for the manager.
+func (m *Manager) SetApp(app fyne.App) {
+	m.app = app
+}
+
+// SetWindow sets the window for the manager.
+func (m *Manager) SetWindow(window fyne.Window) {
+	m.window = window
+}
+
+// SetConnection sets the connection for the manager.
+func (m *Manager) SetConnection(conn *net.Connection) {
+	m.conn = conn
+}
+
+// SetManagers sets the managers for the manager.
+func (m *Manager) SetManagers(managers *managers.Managers) {
+	for _, manager := range *managers {
+		if manager, ok := manager.(*action.Manager); ok {
+			m.actionManager = manager
+		}
+		if manager, ok := manager.(*board.Manager); ok {
+			m.boardManager = manager
+		}
+	}
+}
+
+// Init loads the key bindings and starts listening for keys, if the window has a keyboard.
+func (m *Manager) Init() {
+	m.loadBindings()
+
+	c, ok := m.window.Canvas().(desktop.Canvas)
+	if !ok {
+		return
+	}
+	c.SetOnKeyDown(m.onKeyDown)
+	c.SetOnKeyUp(m.onKeyUp)
+}
+
+// Deinit stops listening for keys.
+func (m *Manager) Deinit() {
+	if c, ok := m.window.Canvas().(desktop.Canvas); ok {
+		c.SetOnKeyDown(nil)
+		c.SetOnKeyUp(nil)
+	}
+}
+
+// loadBindings loads the key bindings from the app preferences, falling back to the defaults.
+func (m *Manager) loadBindings() {
+	entries := m.app.Preferences().StringList("keyBindings")
+	if len(entries) == 0 {
+		m.bindings = DefaultBindings()
+		return
+	}
+	m.bindings = make(map[string][]Key)
+	for _, entry := range entries {
+		name, keyString, ok := strings.Cut(entry, "=")
+		if !ok {
+			fmt.Println("Invalid key binding:", entry)
+			continue
+		}
+		key, err := ParseKey(keyString)
+		if err != nil {
+			fmt.Println("Error parsing key binding:", err)
+			continue
+		}
+		m.bindings[name] = append(m.bindings[name], key)
+	}
+}
+
+// saveBindings saves the key bindings to the app preferences as a list of action=key entries.
+func (m *Manager) saveBindings() {
+	var entries []string
+	for _, name := range Actions() {
+		for _, key := range m.bindings[name] {
+			entries = append(entries, name+"="+key.String())
+		}
+	}
+	m.app.Preferences().SetStringList("keyBindings", entries)
+}
+
+// Bind binds the key to the given action, unbinding it from any other action.
+func (m *Manager) Bind(name string, key Key) {
+	for other, keys := range m.bindings {
+		m.bindings[other] = slices.DeleteFunc(keys, func(k Key) bool {
+			return k == key
+		})
+	}
+	m.bindings[name] = append(m.bindings[name], key)
+	m.saveBindings()
+}
+
+// Unbind removes all keys from the given action.
+func (m *Manager) Unbind(name string) {
+	delete(m.bindings, name)
+	m.saveBindings()
+}
+
+// ResetBindings restores the default key bindings.
+func (m *Manager) ResetBindings() {
+	m.bindings = DefaultBindings()
+	m.saveBindings()
+}
+
+// actionFor returns the action bound to the given event. Keys pinned to a scan code take precedence, so the numpad can be bound apart from the number row.
+func (m *Manager) actionFor(event *fyne.KeyEvent) (string, bool) {
+	var fallback string
+	for _, name := range Actions() {
+		for _, key := range m.bindings[name] {
+			if !key.Matches(event) {
+				continue
+			}
+			if key.ScanCode != 0 {
+				return name, true
+			}
+			if fallback == "" {
+				fallback = name
+			}
+		}
+	}
+	return fallback, fallback != ""
+}
+
+func (m *Manager) onKeyDown(event *fyne.KeyEvent) {
+	if m.capture != nil {
+		// Modifiers change what movement does, so they can't be bound themselves.
+		if isModifier(event.Name) {
+			return
+		}
+		capture := m.capture
+		m.capture = nil
+		if event.Name != fyne.KeyEscape {
+			capture(KeyFromEvent(event))
+		}
+		return
+	}
+	// Leave keys alone while a popup is open.
+	if m.window.Canvas().Overlays().Top() != nil {
+		return
+	}
+	name, ok := m.actionFor(event)
+	if !ok {
+		return
+	}
+
+	if dir := board.DirectionNumber(name); dir != 0 {
+		var modifiers fyne.KeyModifier
+		if drv, ok := fyne.CurrentApp().Driver().(desktop.Driver); ok {
+			modifiers = drv.CurrentKeyModifiers()
+		}
+		m.boardManager.CancelWalk()
+		m.actionManager.SetDirectionFromString(name)
+		key := KeyFromEvent(event)
+		switch {
+		case modifiers&fyne.KeyModifierShift != 0:
+			m.conn.SendCommand(fmt.Sprintf("run %d", dir), 0)
+			m.held[key] = "run_stop"
+		case modifiers&fyne.KeyModifierControl != 0:
+			m.conn.SendCommand(fmt.Sprintf("fire %d", dir), 0)
+			m.held[key] = "fire_stop"
+		default:
+			m.conn.SendCommand(name, 0)
+		}
+		return
+	}
+
+	switch name {
+	case ActionApply:
+		m.conn.SendCommand("apply", 0)
+	case ActionPickup:
+		m.conn.SendCommand("get", 0)
+	default:
+		for i := range slotCount {
+			if name == slotAction(i) {
+				m.actionManager.TriggerAction(i)
+			}
+		}
+	}
+}
+
+func isModifier(name fyne.KeyName) bool {
+	switch name {
+	case desktop.KeyShiftLeft, desktop.KeyShiftRight, desktop.KeyControlLeft, desktop.KeyControlRight, desktop.KeyAltLeft, desktop.KeyAltRight, desktop.KeySuperLeft, desktop.KeySuperRight:
+		return true
+	}
+	return false
+}
+
+func (m *Manager) onKeyUp(event *fyne.KeyEvent) {
+	key := KeyFromEvent(event)
+	if cmd, ok := m.held[key]; ok {
+		m.conn.SendCommand(cmd, 0)
+		delete(m.held, key)
+	}
+}
+
+// keysLabel returns the labels of the keys bound to the given action.
+func (m *Manager) keysLabel(name string) string {
+	var labels []string
+	for _, key := range m.bindings[name] {
+		labels = append(labels, key.Label())
+	}
+	if len(labels) == 0 {
+		return "unbound"
+	}
+	return strings.Join(labels, ", ")
+}
+
+// ShowBindings shows the key bindings, allowing them to be remapped.
+func (m *Manager) ShowBindings() {
+	actions := Actions()
+	var list *widget.List
+	status := widget.NewLabel("")
+
+	list = widget.NewList(
+		func() int {
+			return len(actions)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewLabel(""), container.NewHBox(widget.NewButton("bind", nil), widget.NewButton("clear", nil)), widget.NewLabel(""))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			name := actions[i]
+			c := o.(*fyne.Container)
+			c.Objects[0].(*widget.Label).SetText(m.keysLabel(name))
+			c.Objects[1].(*widget.Label).SetText(name)
+			buttons := c.Objects[2].(*fyne.Container)
+			buttons.Objects[0].(*widget.Button).OnTapped = func() {
+				status.SetText(fmt.Sprintf("Press a key for %s, or escape to cancel.", name))
+				m.capture = func(key Key) {
+					m.Bind(name, key)
+					status.SetText("")
+					list.Refresh()
+				}
+			}
+			buttons.Objects[1].(*widget.Button).OnTapped = func() {
+				m.Unbind(name)
+				list.Refresh()
+			}
+		},
+	)
+
+	reset := widget.NewButton("reset to defaults", func() {
+		m.ResetBindings()
+		list.Refresh()
+	})
+
+	dialog := layouts.NewDialog(m.window)
+	dialog.Full = true
+
+	content := container.NewBorder(nil, container.NewHBox(status, layout.NewSpacer(), reset), nil, nil, list)
+	popup := cfwidgets.NewPopUp(container.New(dialog, content), m.window.Canvas())
+	popup.ShowCentered(m.window.Canvas())
+}
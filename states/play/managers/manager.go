@@ -61,6 +61,14 @@ func (m *Managers) Init() {
 	}
 }
 
+func (m *Managers) Deinit() {
+	for _, manager := range *m {
+		if manager, ok := manager.(Deinitializer); ok {
+			manager.Deinit()
+		}
+	}
+}
+
 func (m *Managers) GetByType(manager Manager) Manager {
 	for _, v := range *m {
 		if reflect.TypeOf(v) == reflect.TypeOf(manager) {
@@ -91,6 +99,10 @@ type Initializer interface {
 	Init()
 }
 
+type Deinitializer interface {
+	Deinit()
+}
+
 type FaceReceiver interface {
 	OnFaceLoaded(faceID int16, faceImage *data.FaceImage)
 }
@@ -20,6 +20,7 @@ import (
 
 // Manager provides storage and handling of player skills.
 type Manager struct {
+	app              fyne.App
 	window           fyne.Window
 	conn             *net.Connection
 	handler          *messages.MessageHandler
@@ -29,6 +30,8 @@ type Manager struct {
 	exp              []uint64
 	sortMode         SortMode
 	sortAsc          bool
+	tracker          *expTracker
+	playerName       string
 }
 
 // SortMode defines how the skills list should be sorted.
@@ -52,6 +55,11 @@ func NewManager() *Manager {
 	return &Manager{}
 }
 
+// SetApp sets the app for the manager.
+func (s *Manager) SetApp(app fyne.App) {
+	s.app = app
+}
+
 // SetWindow sets the window for the manager.
 func (s *Manager) SetWindow(window fyne.Window) {
 	s.window = window
@@ -71,12 +79,24 @@ func (s *Manager) SetHandler(handler *messages.MessageHandler) {
 func (s *Manager) Init() {
 	s.skills = make(map[uint16]Skill)
 	s.knownSkills = make(map[uint16]messages.MessageStatSkill)
+	s.tracker = newExpTracker()
+
+	s.handler.On(&messages.MessagePlayer{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
+		// The player's name is needed to keep exp samples per character.
+		msg := m.(*messages.MessagePlayer)
+		if msg.Name == "" || msg.Name == s.playerName {
+			return
+		}
+		s.playerName = msg.Name
+		s.loadSamples()
+	})
 
 	s.handler.On(&messages.MessageStats{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
 		msg := m.(*messages.MessageStats)
 		for _, stat := range msg.Stats {
 			switch stat := stat.(type) {
 			case *messages.MessageStatSkill:
+				s.recordSkill(uint16(stat.Skill), stat.Level, stat.Exp)
 				s.knownSkills[uint16(stat.Skill)] = *stat
 				s.syncKnownSkills()
 			}
@@ -133,6 +153,16 @@ func (m *Manager) Skill(num uint16) Skill {
 	return m.skills[num]
 }
 
+// SkillID returns the number of the skill with the given name, if it is known.
+func (m *Manager) SkillID(name string) (uint16, bool) {
+	for num, skill := range m.skills {
+		if skill.Name == name {
+			return num, true
+		}
+	}
+	return 0, false
+}
+
 // ExpToNextLevel returns the amount of exp needed to reach the next level for a given skill.
 func (m *Manager) ExpToNextLevel(skill uint16) uint64 {
 	if m.knownSkills[skill].Level >= int8(len(m.exp)) {
@@ -279,7 +309,7 @@ func (m *Manager) ShowSkillsList() {
 		next := m.ExpToNextLevel(uint16(m.knownSkillsSlice[id]))
 		v, p := humanize.ComputeSI(float64(next))
 		f := humanize.SIWithDigits(v, 4, p)
-		info.Segments = data.TextToRichTextSegments(f + " until level " + fmt.Sprintf("%d", m.knownSkills[uint16(m.knownSkillsSlice[id])].Level+1) + "\n" + skill.Description)
+		info.Segments = data.TextToRichTextSegments(f + " until level " + fmt.Sprintf("%d", m.knownSkills[uint16(m.knownSkillsSlice[id])].Level+1) + "\n" + m.rateText(uint16(m.knownSkillsSlice[id])) + "\n" + skill.Description)
 		info.Refresh()
 	}
 
@@ -320,7 +350,10 @@ func (m *Manager) ShowSkillsList() {
 		widget.NewToolbarSeparator(),
 		actionSortDir,
 	)
-	topBar := container.NewHBox(widget.NewLabel("Skills"), topControls)
+	session := widget.NewButton("session", func() {
+		m.ShowSessionSummary()
+	})
+	topBar := container.NewBorder(nil, nil, container.NewHBox(widget.NewLabel("Skills"), topControls), session)
 
 	blep := container.NewBorder(topBar, nil, nil, nil, cnt)
 
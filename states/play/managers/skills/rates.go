@@ -0,0 +1,234 @@
+package skills
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dustin/go-humanize"
+	"github.com/kettek/mobifire/states/play/cfwidgets"
+)
+
+// rateWindow is how far back exp samples are used when working out the rate of exp gain.
+const rateWindow = 30 * time.Minute
+
+// sampleSaveInterval limits how often exp samples are written to preferences, as skill stats arrive with every bit of exp gained.
+const sampleSaveInterval = 30 * time.Second
+
+// expSample is a skill's exp at a point in time.
+type expSample struct {
+	Time time.Time
+	Exp  int64
+}
+
+// expTracker tracks the exp of each skill over time, so that rates of gain can be shown.
+type expTracker struct {
+	samples      map[uint16][]expSample
+	sessionStart time.Time
+	startExp     map[uint16]int64 // The exp of each skill when first seen this session.
+	startLevel   map[uint16]int8
+	lastSave     time.Time
+}
+
+func newExpTracker() *expTracker {
+	return &expTracker{
+		samples:      make(map[uint16][]expSample),
+		sessionStart: time.Now(),
+		startExp:     make(map[uint16]int64),
+		startLevel:   make(map[uint16]int8),
+	}
+}
+
+// add records a sample of the skill's exp, dropping samples that have fallen out of the rate window.
+func (t *expTracker) add(skill uint16, level int8, exp int64, now time.Time) {
+	if _, ok := t.startExp[skill]; !ok {
+		t.startExp[skill] = exp
+		t.startLevel[skill] = level
+	}
+	samples := t.samples[skill]
+	if len(samples) > 0 && samples[len(samples)-1].Exp == exp {
+		return
+	}
+	samples = append(samples, expSample{Time: now, Exp: exp})
+	for len(samples) > 1 && now.Sub(samples[0].Time) > rateWindow {
+		samples = samples[1:]
+	}
+	t.samples[skill] = samples
+}
+
+// rate returns the exp gained per hour by the skill over the rate window.
+func (t *expTracker) rate(skill uint16, now time.Time) float64 {
+	samples := t.samples[skill]
+	if len(samples) < 2 {
+		return 0
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	// Measuring up to now rather than the last sample lets the rate fall off while the skill goes unused.
+	elapsed := max(now.Sub(first.Time), time.Minute)
+	return float64(last.Exp-first.Exp) / elapsed.Hours()
+}
+
+// load restores samples from their saved form, keeping those still within the rate window.
+func (t *expTracker) load(entries []string, now time.Time) {
+	loaded := make(map[uint16][]expSample)
+	for _, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			continue
+		}
+		skill, err1 := strconv.ParseUint(parts[0], 10, 16)
+		unix, err2 := strconv.ParseInt(parts[1], 10, 64)
+		exp, err3 := strconv.ParseInt(parts[2], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		sample := expSample{Time: time.Unix(unix, 0), Exp: exp}
+		if now.Sub(sample.Time) > rateWindow {
+			continue
+		}
+		loaded[uint16(skill)] = append(loaded[uint16(skill)], sample)
+	}
+	// Samples taken since connecting are newer than any that were saved.
+	for skill, samples := range t.samples {
+		loaded[skill] = append(loaded[skill], samples...)
+	}
+	t.samples = loaded
+}
+
+// save returns the samples in their saved form.
+func (t *expTracker) save() []string {
+	var entries []string
+	for skill, samples := range t.samples {
+		for _, sample := range samples {
+			entries = append(entries, fmt.Sprintf("%d:%d:%d", skill, sample.Time.Unix(), sample.Exp))
+		}
+	}
+	return entries
+}
+
+// samplesKey returns the preferences key that the current character's exp samples are stored under. Characters are only unique to a server, so it is keyed by the server as the login is.
+func (m *Manager) samplesKey() string {
+	host := m.app.Preferences().String("lastServer")
+	port := m.app.Preferences().Int("lastPort")
+	return fmt.Sprintf("skillSamples-%s-%d-%s", host, port, m.playerName)
+}
+
+// loadSamples restores the current character's exp samples, so that rates carry over a reconnect.
+func (m *Manager) loadSamples() {
+	m.tracker.load(m.app.Preferences().StringList(m.samplesKey()), time.Now())
+}
+
+// saveSamples stores the current character's exp samples.
+func (m *Manager) saveSamples(now time.Time) {
+	if m.playerName == "" {
+		return
+	}
+	m.tracker.lastSave = now
+	m.app.Preferences().SetStringList(m.samplesKey(), m.tracker.save())
+}
+
+// Deinit saves the exp samples, as those since the last save would otherwise be lost.
+func (m *Manager) Deinit() {
+	m.saveSamples(time.Now())
+}
+
+// recordSkill records a skill stat for exp tracking, announcing when the skill gains a level.
+func (m *Manager) recordSkill(skill uint16, level int8, exp int64) {
+	now := time.Now()
+	if prev, ok := m.knownSkills[skill]; ok && level > prev.Level {
+		cfwidgets.ShowToast(m.window.Canvas(), fmt.Sprintf("%s reached level %d!", m.skills[skill].Name, level), 3*time.Second)
+	}
+	m.tracker.add(skill, level, exp, now)
+	if now.Sub(m.tracker.lastSave) > sampleSaveInterval {
+		m.saveSamples(now)
+	}
+}
+
+// ExpRate returns the exp gained per hour by the skill recently.
+func (m *Manager) ExpRate(skill uint16) float64 {
+	return m.tracker.rate(skill, time.Now())
+}
+
+// TotalExpRate returns the exp gained per hour across all skills recently.
+func (m *Manager) TotalExpRate() float64 {
+	var rate float64
+	now := time.Now()
+	for skill := range m.knownSkills {
+		rate += m.tracker.rate(skill, now)
+	}
+	return rate
+}
+
+// TimeToNextLevel returns the estimated time for the skill to reach its next level at its recent rate of gain, if it is gaining exp.
+func (m *Manager) TimeToNextLevel(skill uint16) (time.Duration, bool) {
+	rate := m.ExpRate(skill)
+	next := m.ExpToNextLevel(skill)
+	if rate <= 0 || next == 0 {
+		return 0, false
+	}
+	return time.Duration(float64(next) / rate * float64(time.Hour)), true
+}
+
+// formatExp formats an amount of exp in the short form used by the skills list.
+func formatExp(exp float64) string {
+	v, p := humanize.ComputeSI(exp)
+	return humanize.SIWithDigits(v, 4, p)
+}
+
+// rateText describes the skill's rate of exp gain and the estimated time to its next level.
+func (m *Manager) rateText(skill uint16) string {
+	rate := m.ExpRate(skill)
+	if rate <= 0 {
+		return "no recent exp gain"
+	}
+	text := formatExp(rate) + " exp/hour"
+	if eta, ok := m.TimeToNextLevel(skill); ok {
+		text += fmt.Sprintf(", next level in %s", eta.Round(time.Minute))
+	}
+	return text
+}
+
+// ShowSessionSummary shows the exp and levels gained in each skill since connecting, along with recent rates.
+func (m *Manager) ShowSessionSummary() {
+	t := m.tracker
+	elapsed := time.Since(t.sessionStart)
+
+	grid := container.NewGridWithColumns(4, bold("skill"), bold("gained"), bold("levels"), bold("exp/hour"))
+	var total int64
+	for _, num := range m.knownSkillsSlice {
+		skill := uint16(num)
+		known := m.knownSkills[skill]
+		gained := known.Exp - t.startExp[skill]
+		levels := known.Level - t.startLevel[skill]
+		if gained <= 0 && levels <= 0 {
+			continue
+		}
+		total += gained
+		grid.Add(widget.NewLabel(m.skills[skill].Name))
+		grid.Add(widget.NewLabel(formatExp(float64(gained))))
+		grid.Add(widget.NewLabel(fmt.Sprintf("%+d", levels)))
+		grid.Add(widget.NewLabel(formatExp(m.ExpRate(skill))))
+	}
+
+	header := widget.NewLabel(fmt.Sprintf("Session: %s, %s exp gained, %s exp/hour overall, %s exp/hour recently",
+		elapsed.Round(time.Minute),
+		formatExp(float64(total)),
+		formatExp(float64(total)/max(elapsed.Hours(), time.Minute.Hours())),
+		formatExp(m.TotalExpRate()),
+	))
+	header.Wrapping = fyne.TextWrapWord
+
+	dialog.ShowCustom("Session Summary", "Close", container.NewBorder(header, nil, nil, nil, container.NewVScroll(grid)), m.window)
+}
+
+// bold returns a bold label, for use as a header.
+func bold(text string) *widget.Label {
+	label := widget.NewLabel(text)
+	label.TextStyle.Bold = true
+	return label
+}
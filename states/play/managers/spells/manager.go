@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"image/color"
 	"slices"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 	"github.com/kettek/mobifire/data"
+	"github.com/kettek/mobifire/net"
 	"github.com/kettek/mobifire/states/play/cfwidgets"
 	"github.com/kettek/mobifire/states/play/layouts"
 	"github.com/kettek/mobifire/states/play/managers"
@@ -22,24 +24,56 @@ type Spell = messages.Spell
 
 // Manager manages spells in the game.
 type Manager struct {
+	app           fyne.App
 	window        fyne.Window
+	conn          *net.Connection
 	handler       *messages.MessageHandler
 	skillsManager *skills.Manager
 	spells        []Spell
 	skills        []uint8
 	popup         *cfwidgets.PopUp
+	sortMode      SortMode
+	sortAsc       bool
+	search        string
+	sp            int16 // The player's current spell points, for showing which spells can be afforded.
+	grace         int16
+	statsKnown    bool
+	onRefresh     func()                // Refreshes the spells list while it is shown.
+	schoolColors  map[uint8]color.NRGBA // User chosen spell backgrounds, keyed by skill ID.
+	schoolTheme   schoolTheme
 }
 
+// SortMode defines how spells are sorted within their skill.
+type SortMode int
+
+// Sort modes for the spells list.
+const (
+	SortByLevel SortMode = iota
+	SortByCost
+	SortByCastingTime
+	SortByName
+)
+
 // NewManager creates a new spell manager.
 func NewManager() *Manager {
 	return &Manager{}
 }
 
+// SetApp sets the app for the manager.
+func (mgr *Manager) SetApp(app fyne.App) {
+	mgr.app = app
+}
+
 // SetWindow sets the window for the manager.
 func (mgr *Manager) SetWindow(window fyne.Window) {
 	mgr.window = window
 }
 
+// SetConnection sets the connection for the manager.
+func (mgr *Manager) SetConnection(conn *net.Connection) {
+	mgr.conn = conn
+}
+
 // SetHandler sets the message handler for the manager.
 func (mgr *Manager) SetHandler(handler *messages.MessageHandler) {
 	mgr.handler = handler
@@ -56,11 +90,32 @@ func (mgr *Manager) SetManagers(managers *managers.Managers) {
 
 // Init sets up handlers for adding, updating, and deleting spells.
 func (mgr *Manager) Init() {
+	mgr.sortAsc = true
+	mgr.loadSchoolColors()
+	mgr.handler.On(&messages.MessageStats{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
+		msg := m.(*messages.MessageStats)
+		changed := false
+		for _, stat := range msg.Stats {
+			switch s := stat.(type) {
+			case *messages.MessageStatSP:
+				mgr.sp = int16(*s)
+				changed = true
+			case *messages.MessageStatGrace:
+				mgr.grace = int16(*s)
+				changed = true
+			}
+		}
+		if changed {
+			mgr.statsKnown = true
+			mgr.refresh()
+		}
+	})
 	mgr.handler.On(&messages.MessageAddSpell{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
 		msg := m.(*messages.MessageAddSpell)
 		mgr.spells = append(mgr.spells, msg.Spells...)
 		mgr.sortSpells()
 		mgr.getSkills()
+		mgr.refresh()
 	})
 	mgr.handler.On(&messages.MessageUpdateSpell{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
 		msg := m.(*messages.MessageUpdateSpell)
@@ -75,19 +130,22 @@ func (mgr *Manager) Init() {
 					spell.Damage = int16(u)
 				}
 			}
+			mgr.sortSpells()
+			mgr.refresh()
 		}
 	})
 	mgr.handler.On(&messages.MessageDeleteSpell{}, nil, func(m messages.Message, mf *messages.MessageFailure) {
 		msg := m.(*messages.MessageDeleteSpell)
 		mgr.deleteSpell(msg.Tag)
+		mgr.refresh()
 	})
 
 }
 
 func (mgr *Manager) getSpell(tag int32) *Spell {
-	for _, spell := range mgr.spells {
-		if spell.Tag == tag {
-			return &spell
+	for i := range mgr.spells {
+		if mgr.spells[i].Tag == tag {
+			return &mgr.spells[i]
 		}
 	}
 	return nil
@@ -102,13 +160,63 @@ func (mgr *Manager) deleteSpell(tag int32) {
 	}
 }
 
+// sortSpells sorts spells by skill, then by the current sort mode.
 func (mgr *Manager) sortSpells() {
-	// For now, sort by skill.
 	slices.SortStableFunc(mgr.spells, func(a, b Spell) int {
-		return int(a.Skill) - int(b.Skill)
+		if a.Skill != b.Skill {
+			return int(a.Skill) - int(b.Skill)
+		}
+		var c int
+		switch mgr.sortMode {
+		case SortByLevel:
+			c = int(a.Level) - int(b.Level)
+		case SortByCost:
+			c = int(a.Mana) + int(a.Grace) - int(b.Mana) - int(b.Grace)
+		case SortByCastingTime:
+			c = int(a.CastingTime) - int(b.CastingTime)
+		case SortByName:
+			c = strings.Compare(a.Name, b.Name)
+		}
+		if !mgr.sortAsc {
+			c = -c
+		}
+		return c
 	})
 }
 
+// setSort sets how spells are sorted and refreshes the spells list.
+func (mgr *Manager) setSort(mode SortMode, asc bool) {
+	mgr.sortMode = mode
+	mgr.sortAsc = asc
+	mgr.sortSpells()
+	mgr.refresh()
+}
+
+// refresh refreshes the spells list, if it is shown.
+func (mgr *Manager) refresh() {
+	if mgr.onRefresh != nil {
+		mgr.onRefresh()
+	}
+}
+
+// Affordable returns if the player has enough spell points and grace to cast the spell. Until the player's stats are known, every spell is affordable.
+func (mgr *Manager) Affordable(spell Spell) bool {
+	if !mgr.statsKnown {
+		return true
+	}
+	return spell.Mana <= mgr.sp && spell.Grace <= mgr.grace
+}
+
+// costString returns the cost of the spell, showing both mana and grace if it needs both.
+func costString(spell Spell) string {
+	if spell.Mana > 0 && spell.Grace > 0 {
+		return fmt.Sprintf("%d/%d", spell.Mana, spell.Grace)
+	} else if spell.Mana > 0 {
+		return fmt.Sprintf("%d", spell.Mana)
+	}
+	return fmt.Sprintf("%d", spell.Grace)
+}
+
 func (mgr *Manager) getSkills() {
 	mgr.skills = nil
 	for _, spell := range mgr.spells {
@@ -125,10 +233,12 @@ func (mgr *Manager) getSkills() {
 	}
 }
 
+// getSpellsBySkill returns the spells of the given skill whose names match the search.
 func (mgr *Manager) getSpellsBySkill(skill uint8) []Spell {
+	search := strings.ToLower(mgr.search)
 	var spells []Spell
 	for _, spell := range mgr.spells {
-		if spell.Skill == skill {
+		if spell.Skill == skill && strings.Contains(strings.ToLower(spell.Name), search) {
 			spells = append(spells, spell)
 		}
 	}
@@ -141,17 +251,29 @@ func (mgr *Manager) ShowSpellsList(onSelect func(spell Spell) bool) {
 	info.Wrapping = fyne.TextWrapWord
 	infoScroll := container.NewVScroll(info)
 
-	makeListForSpells := func(spells []Spell) *widget.List {
+	// Spells can be cast from the list when it is only being browsed.
+	var selected *Spell
+	restoring := false // Set while restoring the selection after a refresh, so it isn't treated as the user selecting.
+	cast := widget.NewButton("cast", func() {
+		if selected != nil {
+			mgr.Cast(*selected)
+		}
+	})
+	cast.Disable()
+
+	// The spells shown in each skill's tab, which are fetched again whenever the list is refreshed.
+	tabSpells := make([][]Spell, len(mgr.skills))
+	makeListForSpells := func(tab int) *widget.List {
 		list := widget.NewList(
 			func() int {
-				return len(spells)
+				return len(tabSpells[tab])
 			},
 			func() fyne.CanvasObject {
 				rect := canvas.NewRectangle(color.NRGBA{255, 255, 255, 100})
 				return container.New(&layouts.SpellEntry{IconSize: data.CurrentFaceSet().Width, Rect: rect}, rect, &canvas.Image{}, widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""))
 			},
 			func(i widget.ListItemID, o fyne.CanvasObject) {
-				spell := spells[i]
+				spell := tabSpells[tab][i]
 				rect := o.(*fyne.Container).Objects[0].(*canvas.Rectangle)
 				icon := o.(*fyne.Container).Objects[1].(*canvas.Image)
 				name := o.(*fyne.Container).Objects[2].(*widget.Label)
@@ -167,38 +289,34 @@ func (mgr *Manager) ShowSpellsList(onSelect func(spell Spell) bool) {
 				icon.Refresh()
 				name.SetText(spell.Name)
 				level.SetText(fmt.Sprintf("%d", spell.Level))
-				if spell.Mana > 0 {
-					mana.SetText(fmt.Sprintf("%d", spell.Mana))
-				} else {
-					mana.SetText(fmt.Sprintf("%d", spell.Grace))
-				}
+				mana.SetText(costString(spell))
 				castingTime.SetText(fmt.Sprintf("%d", spell.CastingTime))
 
-				// This is lame, but for now, hardcode check spell schools.
-				skill := mgr.skillsManager.Skill(uint16(spell.Skill))
-				if skill.Name == "pyromancy" {
-					rect.FillColor = color.NRGBA{200, 0, 0, 100}
-				} else if skill.Name == "evocation" {
-					rect.FillColor = color.NRGBA{0, 0, 200, 100}
-				} else if skill.Name == "sorcery" {
-					rect.FillColor = color.NRGBA{200, 0, 200, 100}
-				} else if skill.Name == "summoning" {
-					rect.FillColor = color.NRGBA{0, 200, 0, 100}
-				} else if skill.Name == "praying" {
-					rect.FillColor = color.NRGBA{200, 200, 0, 100}
+				fill := mgr.SchoolColor(spell.Skill)
+				// Dim spells that can't be afforded right now.
+				if mgr.Affordable(spell) {
+					name.Importance = widget.MediumImportance
 				} else {
-					rect.FillColor = color.NRGBA{200, 200, 200, 100}
+					fill.A = 30
+					name.Importance = widget.LowImportance
 				}
+				rect.FillColor = fill
+				name.Refresh()
 				rect.Refresh()
 			},
 		)
 		list.OnSelected = func(id widget.ListItemID) {
-			if onSelect != nil && onSelect(spells[id]) {
+			if restoring {
 				return
 			}
-			spell := spells[id]
+			if onSelect != nil && onSelect(tabSpells[tab][id]) {
+				return
+			}
+			spell := tabSpells[tab][id]
+			selected = &spell
+			cast.Enable()
 			skill := mgr.skillsManager.Skill(uint16(spell.Skill))
-			text := fmt.Sprintf("[b]%s[/b]\n\n[b]Skill:[/b] %s\n[b]Level:[/b] %d\n[b]Mana:[/b] %d\n[b]Casting Time:[/b] %d\n\n%s\n%s", spell.Name, skill.Name, spell.Level, spell.Mana, spell.CastingTime, spell.Description, spell.Requirements)
+			text := fmt.Sprintf("[b]%s[/b]\n\n[b]Skill:[/b] %s\n[b]Level:[/b] %d\n[b]Mana:[/b] %d\n[b]Grace:[/b] %d\n[b]Casting Time:[/b] %d\n\n%s\n%s", spell.Name, skill.Name, spell.Level, spell.Mana, spell.Grace, spell.CastingTime, spell.Description, spell.Requirements)
 			info.Segments = data.TextToRichTextSegments(text)
 			info.Refresh()
 			infoScroll.ScrollToTop()
@@ -207,9 +325,13 @@ func (mgr *Manager) ShowSpellsList(onSelect func(spell Spell) bool) {
 	}
 
 	var skillTabs []*container.TabItem
-	for _, skillID := range mgr.skills {
+	var lists []*widget.List
+	tabSkills := slices.Clone(mgr.skills) // The skill of each tab, as mgr.skills is rebuilt as spells are added.
+	for i, skillID := range tabSkills {
 		skill := mgr.skillsManager.Skill(uint16(skillID))
-		skillTabs = append(skillTabs, container.NewTabItem("", makeListForSpells(mgr.getSpellsBySkill(skillID))))
+		tabSpells[i] = mgr.getSpellsBySkill(skillID)
+		lists = append(lists, makeListForSpells(i))
+		skillTabs = append(skillTabs, container.NewTabItem("", lists[i]))
 		if face, ok := data.GetFace(int(skill.Face)); ok {
 			skillTabs[len(skillTabs)-1].Icon = face
 		}
@@ -221,14 +343,96 @@ func (mgr *Manager) ShowSpellsList(onSelect func(spell Spell) bool) {
 		list.Select(0)
 	}*/
 
-	cnt := container.New(&layouts.Inventory{}, tabs, infoScroll)
+	var infoPane fyne.CanvasObject = infoScroll
+	if onSelect == nil {
+		infoPane = container.NewBorder(nil, cast, nil, nil, infoScroll)
+	}
+	cnt := container.New(&layouts.Inventory{}, tabs, infoPane)
+
+	// Refreshing happens with every change to spell points, so the selection is kept by tag rather than cleared.
+	mgr.onRefresh = func() {
+		found := false
+		for i := range lists {
+			// Spells of a new skill only show up the next time the list is opened.
+			tabSpells[i] = mgr.getSpellsBySkill(tabSkills[i])
+			index := -1
+			if selected != nil {
+				index = slices.IndexFunc(tabSpells[i], func(spell Spell) bool {
+					return spell.Tag == selected.Tag
+				})
+			}
+			if index != -1 {
+				found = true
+				spell := tabSpells[i][index]
+				selected = &spell
+				restoring = true
+				lists[i].Select(index)
+				restoring = false
+			} else {
+				lists[i].UnselectAll()
+			}
+			lists[i].Refresh()
+		}
+		if !found {
+			selected = nil
+			cast.Disable()
+		}
+	}
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("search")
+	search.SetText(mgr.search)
+	search.OnChanged = func(text string) {
+		mgr.search = text
+		mgr.refresh()
+	}
+
+	var actionSortDir, actionSortLevel, actionSortCost, actionSortTime, actionSortName *widget.ToolbarAction
+	sortDirIcon := func() fyne.Resource {
+		if mgr.sortAsc {
+			return data.GetResource("icon_ascending.png")
+		}
+		return data.GetResource("icon_descending.png")
+	}
+	actionSortDir = widget.NewToolbarAction(sortDirIcon(), func() {
+		mgr.setSort(mgr.sortMode, !mgr.sortAsc)
+		actionSortDir.SetIcon(sortDirIcon())
+	})
+	actionSortLevel = widget.NewToolbarAction(data.GetResource("icon_level.png"), func() {
+		mgr.setSort(SortByLevel, mgr.sortAsc)
+	})
+	actionSortCost = widget.NewToolbarAction(data.GetResource("icon_potion.png"), func() {
+		mgr.setSort(SortByCost, mgr.sortAsc)
+	})
+	actionSortTime = widget.NewToolbarAction(data.GetResource("icon_cast.png"), func() {
+		mgr.setSort(SortByCastingTime, mgr.sortAsc)
+	})
+	actionSortName = widget.NewToolbarAction(data.GetResource("icon_name.png"), func() {
+		mgr.setSort(SortByName, mgr.sortAsc)
+	})
+	topControls := widget.NewToolbar(
+		actionSortLevel,
+		actionSortCost,
+		actionSortTime,
+		actionSortName,
+		widget.NewToolbarSeparator(),
+		actionSortDir,
+	)
+	colors := widget.NewButton("colors", func() {
+		mgr.ShowSchoolColors()
+	})
+	topBar := container.NewBorder(nil, nil, container.NewHBox(widget.NewLabel("Spells"), topControls), colors, search)
 
-	content := container.NewBorder(nil, nil, nil, nil, cnt)
+	content := container.NewBorder(topBar, nil, nil, nil, cnt)
 
 	dialog := layouts.NewDialog(mgr.window)
 	dialog.Full = true
 
+	mgr.resolveSchoolDefaults()
 	mgr.popup = cfwidgets.NewPopUp(container.New(dialog, content), mgr.window.Canvas())
+	mgr.popup.SetOnHide(func() {
+		mgr.onRefresh = nil
+	})
 
 	mgr.popup.ShowCentered(mgr.window.Canvas())
 }
@@ -0,0 +1,122 @@
+package spells
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// maxRecentParameters is how many recently used parameters are remembered per spell.
+const maxRecentParameters = 8
+
+// Presets returns the parameters the user has saved for the named spell.
+func (mgr *Manager) Presets(name string) []string {
+	return mgr.app.Preferences().StringList("spellPresets-" + name)
+}
+
+// RecentParameters returns the parameters recently used with the named spell, most recent first.
+func (mgr *Manager) RecentParameters(name string) []string {
+	return mgr.app.Preferences().StringList("spellRecent-" + name)
+}
+
+// Parameters returns the presets and then the recent parameters of the named spell, without duplicates.
+func (mgr *Manager) Parameters(name string) []string {
+	params := mgr.Presets(name)
+	for _, param := range mgr.RecentParameters(name) {
+		if !slices.Contains(params, param) {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// RememberParameter moves the parameter to the front of the named spell's recent parameters.
+func (mgr *Manager) RememberParameter(name, param string) {
+	if param == "" {
+		return
+	}
+	recent := slices.DeleteFunc(mgr.RecentParameters(name), func(p string) bool {
+		return p == param
+	})
+	recent = append([]string{param}, recent...)
+	if len(recent) > maxRecentParameters {
+		recent = recent[:maxRecentParameters]
+	}
+	mgr.app.Preferences().SetStringList("spellRecent-"+name, recent)
+}
+
+// SetPreset saves or removes the parameter as a preset of the named spell.
+func (mgr *Manager) SetPreset(name, param string, preset bool) {
+	presets := mgr.Presets(name)
+	has := slices.Contains(presets, param)
+	if preset && !has && param != "" {
+		presets = append(presets, param)
+	} else if !preset && has {
+		presets = slices.DeleteFunc(presets, func(p string) bool {
+			return p == param
+		})
+	} else {
+		return
+	}
+	mgr.app.Preferences().SetStringList("spellPresets-"+name, presets)
+}
+
+// PromptParameter asks for the parameter to cast the spell with, offering its presets and recent parameters. If allowDeferred is set, the user may instead choose to be asked each time the spell is cast, in which case cb is called with deferred set and no parameter.
+func (mgr *Manager) PromptParameter(spell Spell, allowDeferred bool, cb func(param string, deferred bool)) {
+	entry := widget.NewSelectEntry(mgr.Parameters(spell.Name))
+	preset := widget.NewCheck("save as preset", nil)
+	entry.OnChanged = func(text string) {
+		preset.SetChecked(slices.Contains(mgr.Presets(spell.Name), text))
+	}
+	if recent := mgr.RecentParameters(spell.Name); len(recent) > 0 {
+		entry.SetText(recent[0])
+	}
+	deferred := widget.NewCheck("ask when cast", func(checked bool) {
+		if checked {
+			entry.Disable()
+			preset.Disable()
+		} else {
+			entry.Enable()
+			preset.Enable()
+		}
+	})
+
+	items := []*widget.FormItem{
+		{Text: "Parameter", Widget: entry},
+		{Text: "", Widget: preset},
+	}
+	if allowDeferred {
+		items = append(items, &widget.FormItem{Text: "", Widget: deferred})
+	}
+	dialog.ShowForm(fmt.Sprintf("Spell Parameter (%s)", spell.Name), "Submit", "Cancel", items, func(b bool) {
+		if !b {
+			return
+		}
+		if deferred.Checked {
+			cb("", true)
+			return
+		}
+		param := strings.TrimSpace(entry.Text)
+		mgr.SetPreset(spell.Name, param, preset.Checked)
+		mgr.RememberParameter(spell.Name, param)
+		cb(param, false)
+	}, mgr.window)
+}
+
+// Cast casts the spell, first asking for its parameter if it takes one.
+func (mgr *Manager) Cast(spell Spell) {
+	if spell.Usage == 0 {
+		mgr.conn.SendCommand(fmt.Sprintf("cast %d", spell.Tag), 1)
+		return
+	}
+	mgr.PromptParameter(spell, false, func(param string, _ bool) {
+		if param != "" {
+			mgr.conn.SendCommand(fmt.Sprintf("cast %d %s", spell.Tag, param), 1)
+		} else {
+			mgr.conn.SendCommand(fmt.Sprintf("cast %d", spell.Tag), 1)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package spells
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/data"
+)
+
+// schoolAlpha is the alpha used for the background of spell entries.
+const schoolAlpha = 100
+
+// standardSchoolColors are the colors of the standard Crossfire spell schools. Skill numbers differ between servers, so these are resolved to skill IDs by name once the skills are known.
+var standardSchoolColors = map[string]color.NRGBA{
+	"pyromancy": {200, 0, 0, schoolAlpha},
+	"evocation": {0, 0, 200, schoolAlpha},
+	"sorcery":   {200, 0, 200, schoolAlpha},
+	"summoning": {0, 200, 0, schoolAlpha},
+	"praying":   {200, 200, 0, schoolAlpha},
+}
+
+// fallbackSchoolColor is used for schools that have no color and no face to derive one from.
+var fallbackSchoolColor = color.NRGBA{200, 200, 200, schoolAlpha}
+
+// schoolTheme holds the default colors of spell schools, keyed by skill ID. Defaults come from the standard schools or, failing that, are derived from each skill's face, which is costly enough to be cached until faces change.
+type schoolTheme struct {
+	sync.Mutex
+	defaults map[uint8]color.NRGBA // Colors of the standard schools, or nil until resolved.
+	derived  map[uint8]color.NRGBA // Colors derived from skill faces.
+}
+
+// resolveSchoolDefaults resolves the standard schools to their skill IDs, unless already done. It does nothing until the skills are known.
+func (mgr *Manager) resolveSchoolDefaults() {
+	t := &mgr.schoolTheme
+	t.Lock()
+	defer t.Unlock()
+	if len(t.defaults) > 0 {
+		return
+	}
+	t.defaults = make(map[uint8]color.NRGBA)
+	for name, c := range standardSchoolColors {
+		if id, ok := mgr.skillsManager.SkillID(name); ok {
+			t.defaults[uint8(id)] = c
+		}
+	}
+}
+
+// OnFaceLoaded forgets the colors derived from the loaded face, as it may have changed.
+func (mgr *Manager) OnFaceLoaded(faceID int16, faceImage *data.FaceImage) {
+	t := &mgr.schoolTheme
+	t.Lock()
+	defer t.Unlock()
+	for skill := range t.derived {
+		if int(mgr.skillsManager.Skill(uint16(skill)).Face) == int(faceID) {
+			delete(t.derived, skill)
+		}
+	}
+}
+
+// loadSchoolColors loads the user's spell school colors, keyed by skill ID, from preferences.
+func (mgr *Manager) loadSchoolColors() {
+	mgr.schoolColors = make(map[uint8]color.NRGBA)
+	for _, entry := range mgr.app.Preferences().StringList("spellSchoolColors") {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		skill, err := strconv.ParseUint(key, 10, 8)
+		if err != nil {
+			continue
+		}
+		rgba, err := strconv.ParseUint(value, 16, 32)
+		if err != nil {
+			continue
+		}
+		mgr.schoolColors[uint8(skill)] = color.NRGBA{uint8(rgba >> 24), uint8(rgba >> 16), uint8(rgba >> 8), uint8(rgba)}
+	}
+}
+
+// saveSchoolColors saves the user's spell school colors to preferences.
+func (mgr *Manager) saveSchoolColors() {
+	var entries []string
+	for skill, c := range mgr.schoolColors {
+		entries = append(entries, fmt.Sprintf("%d=%02x%02x%02x%02x", skill, c.R, c.G, c.B, c.A))
+	}
+	mgr.app.Preferences().SetStringList("spellSchoolColors", entries)
+}
+
+// SchoolColor returns the background color for spells of the given skill. The user's choice is used if there is one, then the color of the standard school, and otherwise a color derived from the skill's face.
+func (mgr *Manager) SchoolColor(skill uint8) color.NRGBA {
+	if c, ok := mgr.schoolColors[skill]; ok {
+		return c
+	}
+	t := &mgr.schoolTheme
+	t.Lock()
+	defer t.Unlock()
+	if c, ok := t.defaults[skill]; ok {
+		return c
+	}
+	if c, ok := t.derived[skill]; ok {
+		return c
+	}
+	face, ok := data.GetFace(int(mgr.skillsManager.Skill(uint16(skill)).Face))
+	if !ok {
+		// The face may yet arrive, so don't cache the fallback.
+		return fallbackSchoolColor
+	}
+	c, ok := averageColor(face)
+	if !ok {
+		c = fallbackSchoolColor
+	}
+	if t.derived == nil {
+		t.derived = make(map[uint8]color.NRGBA)
+	}
+	t.derived[skill] = c
+	return c
+}
+
+// SetSchoolColor sets and persists the color for spells of the given skill.
+func (mgr *Manager) SetSchoolColor(skill uint8, c color.NRGBA) {
+	mgr.schoolColors[skill] = c
+	mgr.saveSchoolColors()
+	mgr.refresh()
+}
+
+// ResetSchoolColor removes the user's color for spells of the given skill, returning it to the default.
+func (mgr *Manager) ResetSchoolColor(skill uint8) {
+	delete(mgr.schoolColors, skill)
+	mgr.saveSchoolColors()
+	mgr.refresh()
+}
+
+// averageColor returns the average color of the opaque pixels of a face, with the alpha used for spell entries.
+func averageColor(face *data.FaceImage) (color.NRGBA, bool) {
+	if face.Image == nil {
+		return color.NRGBA{}, false
+	}
+	var r, g, b, n uint64
+	bounds := face.Image.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(face.Image.At(x, y)).(color.NRGBA)
+			if c.A < 128 {
+				continue
+			}
+			r += uint64(c.R)
+			g += uint64(c.G)
+			b += uint64(c.B)
+			n++
+		}
+	}
+	if n == 0 {
+		return color.NRGBA{}, false
+	}
+	return color.NRGBA{uint8(r / n), uint8(g / n), uint8(b / n), schoolAlpha}, true
+}
+
+// ShowSchoolColors shows a dialog for picking the color of each spell school the player knows.
+func (mgr *Manager) ShowSchoolColors() {
+	mgr.resolveSchoolDefaults()
+	rows := container.NewVBox()
+	for _, skillID := range mgr.skills {
+		skill := mgr.skillsManager.Skill(uint16(skillID))
+
+		icon := &canvas.Image{FillMode: canvas.ImageFillContain}
+		icon.SetMinSize(fyne.NewSize(float32(data.CurrentFaceSet().Width), float32(data.CurrentFaceSet().Height)))
+		if face, ok := data.GetFace(int(skill.Face)); ok {
+			icon.Resource = face
+		} else {
+			icon.Resource = data.GetResource("blank.png")
+		}
+
+		swatch := canvas.NewRectangle(mgr.SchoolColor(skillID))
+		swatch.SetMinSize(fyne.NewSize(48, 24))
+		pick := widget.NewButton("pick", func() {
+			picker := dialog.NewColorPicker(skill.Name, "Spell background color", func(c color.Color) {
+				nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+				nc.A = schoolAlpha
+				mgr.SetSchoolColor(skillID, nc)
+				swatch.FillColor = nc
+				swatch.Refresh()
+			}, mgr.window)
+			picker.Advanced = true
+			picker.SetColor(mgr.SchoolColor(skillID))
+			picker.Show()
+		})
+		reset := widget.NewButton("reset", func() {
+			mgr.ResetSchoolColor(skillID)
+			swatch.FillColor = mgr.SchoolColor(skillID)
+			swatch.Refresh()
+		})
+		rows.Add(container.NewBorder(nil, nil, container.NewHBox(icon, swatch), container.NewHBox(pick, reset), widget.NewLabel(skill.Name)))
+	}
+	dialog.ShowCustom("Spell School Colors", "Close", container.NewVScroll(rows), mgr.window)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/kettek/mobifire/states/play/managers/board"
 	"github.com/kettek/mobifire/states/play/managers/face"
 	"github.com/kettek/mobifire/states/play/managers/items"
+	"github.com/kettek/mobifire/states/play/managers/keys"
 	"github.com/kettek/mobifire/states/play/managers/skills"
 	"github.com/kettek/mobifire/states/play/managers/spells"
 	"github.com/kettek/termfire/messages"
@@ -60,6 +61,7 @@ func NewState(conn *net.Connection, character string) *State {
 	state.managers.Add(spells.NewManager())
 	state.managers.Add(items.NewManager())
 	state.managers.Add(action.NewManager())
+	state.managers.Add(keys.NewManager())
 	return state
 }
 
@@ -89,6 +91,7 @@ func (s *State) Enter(next func(states.State)) (leave func()) {
 	itemsManager := s.managers.GetByType(&items.Manager{}).(*items.Manager)
 	skillsManager := s.managers.GetByType(&skills.Manager{}).(*skills.Manager)
 	spellsManager := s.managers.GetByType(&spells.Manager{}).(*spells.Manager)
+	keysManager := s.managers.GetByType(&keys.Manager{}).(*keys.Manager)
 
 	// Setup commands to show in the commands list.
 	s.commandsManager.commands = []command{
@@ -126,6 +129,12 @@ func (s *State) Enter(next func(states.State)) (leave func()) {
 				s.commandsManager.QuerySimpleCommand("body", messages.MessageTypeCommand, messages.SubMessageTypeCommandBody)
 			},
 		},
+		{
+			Name: "equipment",
+			OnActivate: func() {
+				itemsManager.ShowEquipment()
+			},
+		},
 		{
 			Name: "inventory",
 			OnActivate: func() {
@@ -181,6 +190,18 @@ func (s *State) Enter(next func(states.State)) (leave func()) {
 				q.SubmitText = "Set Title"
 			},
 		},
+		{
+			Name: "pickup",
+			OnActivate: func() {
+				itemsManager.ShowPickup()
+			},
+		},
+		{
+			Name: "keys",
+			OnActivate: func() {
+				keysManager.ShowBindings()
+			},
+		},
 	}
 	s.commandsManager.OnCommandComplete = func(c *queryCommand) {
 		if c.Text == "" {
@@ -290,6 +311,7 @@ func (s *State) Enter(next func(states.State)) (leave func()) {
 		var toolbarCmdAction *widget.ToolbarAction
 		var toolbarApplyAction *widget.ToolbarAction
 		var toolbarGetAction *widget.ToolbarAction
+		var toolbarLootAction *widget.ToolbarAction
 		toolbarCmdAction = widget.NewToolbarAction(data.GetResource("icon_commands.png"), func() {
 			commandsPopup.ShowAtRelativePosition(fyne.NewPos(-toolbarCmdAction.ToolbarObject().Size().Width, 0), toolbarCmdAction.ToolbarObject())
 		})
@@ -299,10 +321,14 @@ func (s *State) Enter(next func(states.State)) (leave func()) {
 		toolbarGetAction = widget.NewToolbarAction(data.GetResource("icon_pickup.png"), func() {
 			s.conn.SendCommand("get", 0)
 		})
+		toolbarLootAction = widget.NewToolbarAction(data.GetResource("icon_get.png"), func() {
+			itemsManager.ShowLootMenu(fyne.NewPos(-toolbarLootAction.ToolbarObject().Size().Width, 0), toolbarLootAction.ToolbarObject())
+		})
 		toolbar = NewToolbar(
 			toolbarCmdAction,
 			toolbarApplyAction,
 			toolbarGetAction,
+			toolbarLootAction,
 			widget.NewToolbarAction(data.GetResource("icon_inventory.png"), func() {
 				itemsManager.ShowInventory(s.playerTag, func(item *items.Item) bool {
 					return false
@@ -324,13 +350,30 @@ func (s *State) Enter(next func(states.State)) (leave func()) {
 	toolbarSized := container.NewThemeOverride(toolbar, sizedTheme)
 	toolbars := container.NewHBox(layout.NewSpacer(), toolbarSized)
 
-	thumbPad := &thumbpadWidget{}
+	thumbPad := newThumbpad(s.app.Preferences())
 	thumbPad.onCommand = func(cmd string) {
+		boardManager.CancelWalk()
 		s.conn.SendCommand(cmd, 0)
-		actionManager.SetDirectionFromString(cmd)
 	}
+	thumbPad.onDirection = actionManager.SetDirectionFromString
 	thumbPadContainer := container.New(layout.NewStackLayout(), thumbPad)
 
+	boardManager.OnCommand = func(cmd string) {
+		actionManager.SetDirectionFromString(cmd)
+	}
+	// The game layout only makes room for the left area while the thumbpad is shown, so hiding it gives its space over to the board.
+	boardManager.OnThumbpadHidden = func(hidden bool) {
+		if hidden {
+			thumbPadContainer.Hide()
+		} else {
+			thumbPadContainer.Show()
+		}
+		if s.container != nil {
+			s.container.Refresh()
+		}
+	}
+	boardManager.OnThumbpadHidden(boardManager.ThumbpadHidden())
+
 	leftAreaToolbarTop := container.NewThemeOverride(container.New(layout.NewGridLayout(4),
 		actionManager.AcquireButton(0),
 		actionManager.AcquireButton(1),
@@ -346,16 +389,22 @@ func (s *State) Enter(next func(states.State)) (leave func()) {
 
 	leftArea := container.New(&layouts.Left{}, leftAreaToolbarTop, thumbPadContainer, leftAreaToolbarBot)
 
+	floorPanel := container.NewThemeOverride(itemsManager.GetFloorPanel().Container(), sizedTheme)
+
 	s.container = container.New(&layouts.Game{
 		Board:    boardManager.CanvasObject(),
 		Messages: messagesList,
 		Left:     leftArea,
 		Right:    toolbars,
-	}, boardManager.CanvasObject(), container.NewStack(messagesListBackground, container.NewThemeOverride(messagesList, sizedTheme)), leftArea, toolbars)
+		Floor:    floorPanel,
+		Thumbpad: thumbPadContainer,
+	}, boardManager.CanvasObject(), container.NewStack(messagesListBackground, container.NewThemeOverride(messagesList, sizedTheme)), floorPanel, leftArea, toolbars)
 
 	//s.container = container.New(layout.NewCenterLayout(), vcontainer)
 
-	return nil
+	return func() {
+		s.managers.Deinit()
+	}
 }
 
 // Container returns the container.
@@ -1,33 +1,94 @@
 package play
 
 import (
+	"fmt"
 	"image/color"
-	"log"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/kettek/mobifire/states/play/managers/board"
+)
+
+// thumbpadMode determines what dragging on the thumbpad does.
+type thumbpadMode int
+
+const (
+	thumbpadWalk thumbpadMode = iota // Step repeatedly while held.
+	thumbpadRun                      // Run until released.
+	thumbpadFire                     // Fire until released.
+)
+
+var thumbpadModeNames = []string{"walk", "run", "fire"}
+
+func (m thumbpadMode) String() string {
+	if m < 0 || int(m) >= len(thumbpadModeNames) {
+		return ""
+	}
+	return thumbpadModeNames[m]
+}
+
+const (
+	defaultThumbpadDeadZone = 20                     // How far a drag must go before it points somewhere.
+	defaultThumbpadInterval = 100 * time.Millisecond // The least time between commands sent from a drag.
+	thumbpadWalkRepeat      = 300 * time.Millisecond // How often steps are repeated while held in walk mode.
+	thumbpadFlashDuration   = 150 * time.Millisecond // How long the indicator shows after a tap.
 )
 
 type thumbpadWidget struct {
 	widget.BaseWidget
-	dragging  bool
-	startPos  fyne.Position
-	deltaPos  fyne.Position
-	lastDirX  int
-	lastDirY  int
+	prefs    fyne.Preferences
+	mu       sync.Mutex
+	mode     thumbpadMode
+	deadZone float32
+	interval time.Duration
+	dragging bool
+	flashing bool
+	startPos fyne.Position
+	lastPos  fyne.Position
+	current  string // The direction the drag is pointing, if any.
+	sent     string // The direction last sent to the server.
+	lastSend time.Time
+	timer    *time.Timer
+	// onCommand is called with each command to send.
 	onCommand func(cmd string)
+	// onDirection is called whenever the thumbpad is pointed in a direction.
+	onDirection func(dir string)
+}
+
+func newThumbpad(prefs fyne.Preferences) *thumbpadWidget {
+	r := &thumbpadWidget{
+		prefs:    prefs,
+		mode:     thumbpadMode(prefs.Int("thumbpadMode")),
+		deadZone: float32(prefs.FloatWithFallback("thumbpadDeadZone", defaultThumbpadDeadZone)),
+		interval: time.Duration(prefs.IntWithFallback("thumbpadInterval", int(defaultThumbpadInterval/time.Millisecond))) * time.Millisecond,
+	}
+	if r.mode.String() == "" {
+		r.mode = thumbpadWalk
+	}
+	r.ExtendBaseWidget(r)
+	return r
 }
 
 func (r *thumbpadWidget) CreateRenderer() fyne.WidgetRenderer {
 	r.ExtendBaseWidget(r)
-	return &thumbpadWidgetRenderer{
+	renderer := &thumbpadWidgetRenderer{
+		pad: r,
 		rect: &canvas.Rectangle{
 			StrokeColor: theme.Color(theme.ColorNameForeground),
 			StrokeWidth: 1,
 		},
+		ring:  &canvas.Circle{StrokeWidth: 1},
+		line:  &canvas.Line{StrokeWidth: 2},
+		knob:  &canvas.Circle{},
+		label: canvas.NewText("", theme.Color(theme.ColorNameForeground)),
 	}
+	renderer.label.Alignment = fyne.TextAlignCenter
+	renderer.Refresh()
+	return renderer
 }
 
 func (r *thumbpadWidget) MinSize() fyne.Size {
@@ -35,114 +96,280 @@ func (r *thumbpadWidget) MinSize() fyne.Size {
 	return r.BaseWidget.MinSize()
 }
 
+// SetMode sets and stores the thumbpad's mode.
+func (r *thumbpadWidget) SetMode(mode thumbpadMode) {
+	r.mu.Lock()
+	r.mode = mode
+	r.mu.Unlock()
+	r.prefs.SetInt("thumbpadMode", int(mode))
+	r.Refresh()
+}
+
+// SetDeadZone sets and stores how far a drag must go before it points somewhere.
+func (r *thumbpadWidget) SetDeadZone(deadZone float32) {
+	r.mu.Lock()
+	r.deadZone = deadZone
+	r.mu.Unlock()
+	r.prefs.SetFloat("thumbpadDeadZone", float64(deadZone))
+	r.Refresh()
+}
+
+// SetInterval sets and stores the least time between commands sent from a drag.
+func (r *thumbpadWidget) SetInterval(interval time.Duration) {
+	r.mu.Lock()
+	r.interval = interval
+	r.mu.Unlock()
+	r.prefs.SetInt("thumbpadInterval", int(interval/time.Millisecond))
+}
+
+// directionAt returns the direction from the center of the thumbpad to the given position. An empty string is returned within the dead zone.
+func (r *thumbpadWidget) directionAt(pos fyne.Position) string {
+	size := r.Size()
+	return r.directionFrom(fyne.NewPos(size.Width/2, size.Height/2), pos)
+}
+
+// directionFrom returns the direction from one position to another. An empty string is returned within the dead zone.
+func (r *thumbpadWidget) directionFrom(from, to fyne.Position) string {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	if dx*dx+dy*dy < r.deadZone*r.deadZone {
+		return ""
+	}
+	return board.DirectionFromVector(dx, dy)
+}
+
+// Tapped steps in the tapped direction, or fires in fire mode. Tapping the center cycles through the modes.
 func (r *thumbpadWidget) Tapped(event *fyne.PointEvent) {
-	dirX := 0
-	dirY := 0
-	w := r.Size().Width
-	h := r.Size().Height
-	buttonWidth := w / 3
-	buttonHeight := h / 3
+	r.mu.Lock()
+	dir := r.directionAt(event.Position)
+	if dir == "" {
+		next := (r.mode + 1) % thumbpadMode(len(thumbpadModeNames))
+		r.mu.Unlock()
+		r.SetMode(next)
+		return
+	}
+	if r.mode == thumbpadFire {
+		r.fire(dir)
+	} else {
+		r.step(dir)
+	}
+	r.mu.Unlock()
+	r.flash(event.Position)
+}
 
-	if event.Position.Y < buttonHeight {
-		dirY = -1
+// TappedSecondary fires in the tapped direction. Long-pressing the center shows the thumbpad's settings.
+func (r *thumbpadWidget) TappedSecondary(event *fyne.PointEvent) {
+	r.mu.Lock()
+	dir := r.directionAt(event.Position)
+	if dir != "" {
+		r.fire(dir)
 	}
-	if event.Position.Y > h-buttonHeight {
-		dirY = 1
+	r.mu.Unlock()
+	if dir == "" {
+		r.showMenu(event.AbsolutePosition)
+		return
 	}
-	if event.Position.X < buttonWidth {
-		dirX = -1
+	r.flash(event.Position)
+}
+
+// flash briefly shows the indicator pointing at the given position.
+func (r *thumbpadWidget) flash(pos fyne.Position) {
+	size := r.Size()
+	r.mu.Lock()
+	r.flashing = true
+	r.startPos = fyne.NewPos(size.Width/2, size.Height/2)
+	r.lastPos = pos
+	r.mu.Unlock()
+	r.Refresh()
+	time.AfterFunc(thumbpadFlashDuration, func() {
+		r.mu.Lock()
+		r.flashing = false
+		r.mu.Unlock()
+		r.Refresh()
+	})
+}
+
+func (r *thumbpadWidget) showMenu(pos fyne.Position) {
+	c := fyne.CurrentApp().Driver().CanvasForObject(r)
+	if c == nil {
+		return
 	}
-	if event.Position.X > w-buttonWidth {
-		dirX = 1
+	r.mu.Lock()
+	mode, deadZone, interval := r.mode, r.deadZone, r.interval
+	r.mu.Unlock()
+
+	var items []*fyne.MenuItem
+	for i, name := range thumbpadModeNames {
+		item := fyne.NewMenuItem(name, func() {
+			r.SetMode(thumbpadMode(i))
+		})
+		item.Checked = mode == thumbpadMode(i)
+		items = append(items, item)
 	}
 
-	if dirX == -1 && dirY == -1 {
-		r.command("northwest")
-	} else if dirX == 0 && dirY == -1 {
-		r.command("north")
-	} else if dirX == 1 && dirY == -1 {
-		r.command("northeast")
-	} else if dirX == -1 && dirY == 0 {
-		r.command("west")
-	} else if dirX == 1 && dirY == 0 {
-		r.command("east")
-	} else if dirX == -1 && dirY == 1 {
-		r.command("southwest")
-	} else if dirX == 0 && dirY == 1 {
-		r.command("south")
-	} else if dirX == 1 && dirY == 1 {
-		r.command("southeast")
+	deadZoneItem := fyne.NewMenuItem("dead zone", nil)
+	var deadZoneItems []*fyne.MenuItem
+	for _, size := range []float32{10, 20, 40} {
+		item := fyne.NewMenuItem(fmt.Sprintf("%.0f", size), func() {
+			r.SetDeadZone(size)
+		})
+		item.Checked = deadZone == size
+		deadZoneItems = append(deadZoneItems, item)
 	}
-}
+	deadZoneItem.ChildMenu = fyne.NewMenu("", deadZoneItems...)
 
-func (r *thumbpadWidget) TappedSecondary(event *fyne.PointEvent) {
-	log.Println("TappedSecondary", event)
+	intervalItem := fyne.NewMenuItem("rate limit", nil)
+	var intervalItems []*fyne.MenuItem
+	for _, ms := range []time.Duration{50, 100, 200} {
+		item := fyne.NewMenuItem(fmt.Sprintf("%dms", ms), func() {
+			r.SetInterval(ms * time.Millisecond)
+		})
+		item.Checked = interval == ms*time.Millisecond
+		intervalItems = append(intervalItems, item)
+	}
+	intervalItem.ChildMenu = fyne.NewMenu("", intervalItems...)
+
+	items = append(items, fyne.NewMenuItemSeparator(), deadZoneItem, intervalItem)
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), c, pos)
 }
 
 func (r *thumbpadWidget) command(cmd string) {
 	if r.onCommand != nil {
 		r.onCommand(cmd)
 	}
+	r.lastSend = time.Now()
+}
+
+func (r *thumbpadWidget) point(dir string) {
+	if r.onDirection != nil {
+		r.onDirection(dir)
+	}
+}
+
+// step takes a single step in the given direction.
+func (r *thumbpadWidget) step(dir string) {
+	r.point(dir)
+	r.command(dir)
+}
+
+// fire fires once in the given direction.
+func (r *thumbpadWidget) fire(dir string) {
+	r.point(dir)
+	r.command(fmt.Sprintf("fire %d", board.DirectionNumber(dir)))
+	r.command("fire_stop")
 }
 
 func (r *thumbpadWidget) Dragged(event *fyne.DragEvent) {
+	r.mu.Lock()
 	if !r.dragging {
-		r.startPos = event.Position
 		r.dragging = true
-		r.command("run")
-	} else {
-		r.deltaPos = fyne.NewPos(event.Position.X-r.startPos.X, event.Position.Y-r.startPos.Y)
-		dirX := 0
-		dirY := 0
-		if r.deltaPos.X < -20 {
-			dirX = -1
-		} else if r.deltaPos.X > 20 {
-			dirX = 1
+		r.startPos = event.Position.Subtract(event.Dragged)
+	}
+	r.lastPos = event.Position
+	r.current = r.directionFrom(r.startPos, r.lastPos)
+	r.update()
+	r.mu.Unlock()
+	r.Refresh()
+}
+
+// update sends whatever commands are needed for the drag's current direction, deferring them if commands were sent too recently. The caller must hold mu.
+func (r *thumbpadWidget) update() {
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	if !r.dragging {
+		return
+	}
+
+	since := time.Since(r.lastSend)
+	if r.mode == thumbpadWalk {
+		if r.current == "" {
+			r.sent = ""
+			return
 		}
-		if r.deltaPos.Y < -20 {
-			dirY = -1
-		} else if r.deltaPos.Y > 20 {
-			dirY = 1
+		wait := r.interval - since
+		if r.current == r.sent {
+			wait = thumbpadWalkRepeat - since
 		}
-		// TODO: commands need to have some sort of throttling, as we probably don't want to spam the server per drag event.
-		if dirX != r.lastDirX || dirY != r.lastDirY {
-			if dirX == -1 && dirY == -1 {
-				r.command("northwest")
-			} else if dirX == 0 && dirY == -1 {
-				r.command("north")
-			} else if dirX == 1 && dirY == -1 {
-				r.command("northeast")
-			} else if dirX == -1 && dirY == 0 {
-				r.command("west")
-			} else if dirX == 1 && dirY == 0 {
-				r.command("east")
-			} else if dirX == -1 && dirY == 1 {
-				r.command("southwest")
-			} else if dirX == 0 && dirY == 1 {
-				r.command("south")
-			} else if dirX == 1 && dirY == 1 {
-				r.command("southeast")
-			}
-			r.lastDirX = dirX
-			r.lastDirY = dirY
+		if wait <= 0 {
+			r.step(r.current)
+			r.sent = r.current
+			wait = thumbpadWalkRepeat
+		}
+		r.timer = time.AfterFunc(wait, r.onTimer)
+		return
+	}
+
+	if r.current == r.sent {
+		return
+	}
+	if since < r.interval {
+		r.timer = time.AfterFunc(r.interval-since, r.onTimer)
+		return
+	}
+	if r.current == "" {
+		r.stop()
+		return
+	}
+	r.point(r.current)
+	if r.mode == thumbpadRun {
+		r.command(fmt.Sprintf("run %d", board.DirectionNumber(r.current)))
+	} else {
+		r.command(fmt.Sprintf("fire %d", board.DirectionNumber(r.current)))
+	}
+	r.sent = r.current
+}
+
+func (r *thumbpadWidget) onTimer() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timer = nil
+	r.update()
+}
+
+// stop stops running or firing. The caller must hold mu.
+func (r *thumbpadWidget) stop() {
+	if r.sent != "" && r.mode != thumbpadWalk {
+		if r.mode == thumbpadRun {
+			r.command("run_stop")
+		} else {
+			r.command("fire_stop")
 		}
-		//r.Refresh() ???
 	}
+	r.sent = ""
 }
 
 func (r *thumbpadWidget) DragEnd() {
+	r.mu.Lock()
 	if r.dragging {
-		r.command("run_stop")
 		r.dragging = false
-		r.lastDirX = 0
-		r.lastDirY = 0
+		if r.timer != nil {
+			r.timer.Stop()
+			r.timer = nil
+		}
+		r.stop()
+		r.current = ""
 	}
+	r.mu.Unlock()
+	r.Refresh()
+}
+
+// indicator returns what the renderer should show.
+func (r *thumbpadWidget) indicator() (mode thumbpadMode, deadZone float32, active bool, start, end fyne.Position) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.mode, r.deadZone, r.dragging || r.flashing, r.startPos, r.lastPos
 }
 
 var _ fyne.WidgetRenderer = (*thumbpadWidgetRenderer)(nil)
 
 type thumbpadWidgetRenderer struct {
-	rect *canvas.Rectangle
+	pad   *thumbpadWidget
+	rect  *canvas.Rectangle
+	ring  *canvas.Circle // The dead zone around where the drag started.
+	line  *canvas.Line
+	knob  *canvas.Circle // Where the drag currently is.
+	label *canvas.Text   // The current mode.
 }
 
 func (r *thumbpadWidgetRenderer) BackgroundColor() color.Color {
@@ -154,6 +381,21 @@ func (r *thumbpadWidgetRenderer) Destroy() {
 
 func (r *thumbpadWidgetRenderer) Layout(size fyne.Size) {
 	r.rect.Resize(size)
+	labelSize := r.label.MinSize()
+	r.label.Resize(fyne.NewSize(size.Width, labelSize.Height))
+	r.label.Move(fyne.NewPos(0, size.Height-labelSize.Height-theme.Padding()))
+	r.layoutIndicator()
+}
+
+func (r *thumbpadWidgetRenderer) layoutIndicator() {
+	_, deadZone, _, start, end := r.pad.indicator()
+	r.ring.Move(fyne.NewPos(start.X-deadZone, start.Y-deadZone))
+	r.ring.Resize(fyne.NewSize(deadZone*2, deadZone*2))
+	r.line.Position1 = start
+	r.line.Position2 = end
+	knob := deadZone / 2
+	r.knob.Move(fyne.NewPos(end.X-knob, end.Y-knob))
+	r.knob.Resize(fyne.NewSize(knob*2, knob*2))
 }
 
 func (r *thumbpadWidgetRenderer) MinSize() fyne.Size {
@@ -161,9 +403,37 @@ func (r *thumbpadWidgetRenderer) MinSize() fyne.Size {
 }
 
 func (r *thumbpadWidgetRenderer) Objects() []fyne.CanvasObject {
-	return []fyne.CanvasObject{r.rect}
+	return []fyne.CanvasObject{r.rect, r.label, r.ring, r.line, r.knob}
 }
 
 func (r *thumbpadWidgetRenderer) Refresh() {
+	mode, _, active, _, _ := r.pad.indicator()
+
+	clr := theme.Color(theme.ColorNameForeground)
+	switch mode {
+	case thumbpadRun:
+		clr = theme.Color(theme.ColorNamePrimary)
+	case thumbpadFire:
+		clr = theme.Color(theme.ColorNameError)
+	}
+	r.label.Text = mode.String()
+	r.label.Color = clr
+	r.ring.StrokeColor = clr
+	r.line.StrokeColor = clr
+	r.knob.FillColor = clr
+
+	for _, o := range []fyne.CanvasObject{r.ring, r.line, r.knob} {
+		if active {
+			o.Show()
+		} else {
+			o.Hide()
+		}
+	}
+	r.layoutIndicator()
+
 	r.rect.Refresh()
+	r.label.Refresh()
+	r.ring.Refresh()
+	r.line.Refresh()
+	r.knob.Refresh()
 }